@@ -0,0 +1,182 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wzyjerry/windranger/internal/ir"
+	"github.com/wzyjerry/windranger/internal/parser"
+	"github.com/wzyjerry/windranger/internal/version"
+)
+
+const (
+	// KindDocument 文档解析结果
+	KindDocument = "document"
+	// KindRender 渲染结果
+	KindRender = "render"
+)
+
+// Format 缓存格式版本，解析结果、IR或渲染输出的格式变化时递增
+const Format = "1"
+
+// Cache 按内容哈希存取的磁盘缓存，nil表示不使用缓存
+//
+// 键包含缓存格式和windranger版本，升级后旧的缓存自动失效；
+// 开发版本的版本号不随代码变化，键中改为包含可执行文件的哈希
+type Cache struct {
+	dir string
+}
+
+// Dir 默认缓存目录
+func Dir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "windranger"), nil
+}
+
+// Open 打开缓存目录，dir为空时使用默认缓存目录
+func Open(dir string) (*Cache, error) {
+	if dir == "" {
+		var err error
+		if dir, err = Dir(); err != nil {
+			return nil, err
+		}
+	}
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, err
+	}
+	return &Cache{dir: dir}, nil
+}
+
+var (
+	buildOnce sync.Once
+	buildID   string
+)
+
+// build 当前程序的标识，发布版本为版本号，开发版本及包含未提交修改的版本加入可执行文件的哈希
+func build() string {
+	buildOnce.Do(func() {
+		buildID = version.Get()
+		if buildID != "dev" && !strings.HasSuffix(buildID, "+dirty") {
+			return
+		}
+		sum, err := executableHash()
+		if err != nil {
+			// 无法识别时每次运行使用不同的键，相当于不使用缓存
+			sum = time.Now().Format(time.RFC3339Nano)
+		}
+		buildID += "+" + sum
+	})
+	return buildID
+}
+
+// executableHash 当前可执行文件的哈希
+func executableHash() (string, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+	file, err := os.Open(executable)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// Key 计算缓存键，各部分之间以'\0'分隔
+func Key(parts ...string) string {
+	hash := sha256.New()
+	hash.Write([]byte(Format))
+	hash.Write([]byte{0})
+	hash.Write([]byte(build()))
+	for _, part := range parts {
+		hash.Write([]byte{0})
+		hash.Write([]byte(part))
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func (c *Cache) path(kind string, key string) string {
+	return filepath.Join(c.dir, kind, key[:2], key)
+}
+
+// Get 读取缓存
+func (c *Cache) Get(kind string, key string) ([]byte, bool) {
+	if c == nil {
+		return nil, false
+	}
+	content, err := os.ReadFile(c.path(kind, key))
+	if err != nil {
+		return nil, false
+	}
+	return content, true
+}
+
+// Put 写入缓存，先写临时文件再重命名，避免并发运行时读到不完整的内容
+func (c *Cache) Put(kind string, key string, content []byte) error {
+	if c == nil {
+		return nil
+	}
+	path := c.path(kind, key)
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+	temp, err := os.CreateTemp(filepath.Dir(path), key+".*")
+	if err != nil {
+		return err
+	}
+	_, err = temp.Write(content)
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(temp.Name(), path)
+	}
+	if err != nil {
+		_ = os.Remove(temp.Name())
+	}
+	return err
+}
+
+// documents 文档解析结果缓存
+type documents struct {
+	cache *Cache
+}
+
+// Documents 将缓存用作解析器的文档缓存，缓存读写失败时视为未命中
+func (c *Cache) Documents() parser.Cache {
+	return &documents{cache: c}
+}
+
+func (d *documents) Load(name string, content []byte) (*parser.Package, bool) {
+	data, ok := d.cache.Get(KindDocument, Key(name, string(content)))
+	if !ok {
+		return nil, false
+	}
+	pack, err := ir.UnmarshalPackage(data)
+	if err != nil {
+		return nil, false
+	}
+	return pack, true
+}
+
+func (d *documents) Store(name string, content []byte, pack *parser.Package) {
+	data, err := ir.MarshalPackage(pack)
+	if err != nil {
+		return
+	}
+	_ = d.cache.Put(KindDocument, Key(name, string(content)), data)
+}
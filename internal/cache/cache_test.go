@@ -0,0 +1,131 @@
+package cache
+
+import (
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/wzyjerry/windranger/internal/parser"
+)
+
+const (
+	demo = `version: v1
+kind: Model
+metadata:
+  name: demo
+  indexes:
+    - keys: [name]
+spec:
+  # 示例
+  demo:
+    id!: objectid # 主键
+    name: string # 名称
+    author: author # 作者
+`
+	author = `version: v1
+kind: Model
+metadata:
+  name: author
+spec:
+  # 作者
+  author:
+    name: string # 姓名
+    gender: gender # 性别
+`
+	common = `version: v1
+kind: Model
+metadata:
+  name: common
+spec:
+  # 性别
+  gender:
+    - male # 男
+    - female # 女
+  # 标签
+  tag:
+    name: string # 名称
+`
+)
+
+// countingCache 记录命中次数的文档缓存，解析器会并发调用
+type countingCache struct {
+	parser.Cache
+	hits int64
+}
+
+func (c *countingCache) Load(name string, content []byte) (*parser.Package, bool) {
+	pack, ok := c.Cache.Load(name, content)
+	if ok {
+		atomic.AddInt64(&c.hits, 1)
+	}
+	return pack, ok
+}
+
+func parse(t *testing.T, c parser.Cache) []*parser.Package {
+	p := parser.NewParser().
+		AddYamlFile("demo.yaml", []byte(demo)).
+		AddYamlFile("author.yaml", []byte(author)).
+		AddYamlFile("common.yaml", []byte(common))
+	if c != nil {
+		p.SetCache(c)
+	}
+	packages, errs := p.Parse()
+	require.Nil(t, errs)
+	return packages
+}
+
+func TestCache(t *testing.T) {
+	validator := require.New(t)
+	c, err := Open(t.TempDir())
+	validator.Nil(err)
+	_, ok := c.Get(KindRender, Key("missing"))
+	validator.False(ok)
+	validator.Nil(c.Put(KindRender, Key("a", "b"), []byte("content")))
+	content, ok := c.Get(KindRender, Key("a", "b"))
+	validator.True(ok)
+	validator.Equal("content", string(content))
+	// 各部分之间有分隔符
+	validator.NotEqual(Key("a", "b"), Key("ab"))
+	// 开发版本的键包含可执行文件的哈希
+	validator.Regexp(`^dev\+[0-9a-f]{64}$`, build())
+
+	// nil表示不使用缓存
+	var disabled *Cache
+	validator.Nil(disabled.Put(KindRender, Key("a"), []byte("content")))
+	_, ok = disabled.Get(KindRender, Key("a"))
+	validator.False(ok)
+}
+
+func TestDocuments(t *testing.T) {
+	validator := require.New(t)
+	c, err := Open(t.TempDir())
+	validator.Nil(err)
+	documents := &countingCache{Cache: c.Documents()}
+	expected := parse(t, nil)
+	validator.Equal(expected, parse(t, documents))
+	validator.Equal(int64(0), atomic.LoadInt64(&documents.hits))
+	validator.Equal(expected, parse(t, documents))
+	validator.Equal(int64(3), atomic.LoadInt64(&documents.hits))
+}
+
+func TestClosure(t *testing.T) {
+	validator := require.New(t)
+	packages := parse(t, nil)
+	names := func(packages []*parser.Package) []string {
+		result := make([]string, len(packages))
+		for i, pack := range packages {
+			result[i] = pack.Name
+		}
+		return result
+	}
+	validator.Equal([]string{"author", "demo", parser.CommonPackage}, names(packages))
+	validator.Equal([]string{"author", "demo", parser.CommonPackage}, names(Closure(packages, packages[1])))
+	validator.Equal([]string{"author", parser.CommonPackage}, names(Closure(packages, packages[0])))
+	validator.Equal([]string{parser.CommonPackage}, names(Closure(packages, packages[2])))
+
+	// 依赖项目的同名类型不影响本项目的闭包
+	dep := &parser.Package{Name: "author", Module: "base", Structures: []*parser.Structure{{Name: "author"}}}
+	closure := Closure(append(packages, dep), packages[1])
+	validator.Equal([]string{"author", "demo", parser.CommonPackage}, names(closure))
+	validator.NotContains(closure, dep)
+}
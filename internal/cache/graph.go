@@ -0,0 +1,63 @@
+package cache
+
+import (
+	"sort"
+
+	"github.com/wzyjerry/windranger/internal/parser"
+)
+
+// Closure 包及其字段类型直接或间接引用的全部包，按包名排序
+//
+// 包的渲染结果只取决于闭包中的包，闭包的哈希不变时可以复用渲染结果。
+// 本项目和依赖项目定义了同名类型时，按引用方所在的项目解析
+func Closure(packages []*parser.Package, pack *parser.Package) []*parser.Package {
+	type key struct {
+		module string
+		name   string
+	}
+	owners := make(map[key]*parser.Package)
+	names := make(map[string]*parser.Package)
+	for _, p := range packages {
+		for _, enum := range p.Enums {
+			owners[key{p.Module, enum.Name}] = p
+			names[enum.Name] = p
+		}
+		for _, structure := range p.Structures {
+			owners[key{p.Module, structure.Name}] = p
+			names[structure.Name] = p
+		}
+	}
+	owner := func(module string, name string) (*parser.Package, bool) {
+		if p, ok := owners[key{module, name}]; ok {
+			return p, true
+		}
+		p, ok := names[name]
+		return p, ok
+	}
+	visited := map[*parser.Package]struct{}{pack: {}}
+	queue := []*parser.Package{pack}
+	for len(queue) != 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, structure := range current.Structures {
+			for _, field := range structure.Fields {
+				next, ok := owner(current.Module, field.Type.Raw)
+				if !ok {
+					continue
+				}
+				if _, ok := visited[next]; !ok {
+					visited[next] = struct{}{}
+					queue = append(queue, next)
+				}
+			}
+		}
+	}
+	closure := make([]*parser.Package, 0, len(visited))
+	for p := range visited {
+		closure = append(closure, p)
+	}
+	sort.SliceStable(closure, func(i, j int) bool {
+		return closure[i].Name < closure[j].Name
+	})
+	return closure
+}
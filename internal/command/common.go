@@ -1,12 +1,36 @@
 package command
 
-import "strings"
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/wzyjerry/windranger/internal/cache"
+	"github.com/wzyjerry/windranger/internal/output"
+	"github.com/wzyjerry/windranger/internal/parser"
+	"github.com/wzyjerry/windranger/internal/version"
+)
 
 type (
 	// Config 配置
 	Config struct {
 		// Out 生成根目录
 		Out string
+		// Check 只检查生成的文件是否过期，不写入
+		Check bool
+		// Diff 只输出与磁盘文件的差异，不写入
+		Diff bool
+		// Force 覆盖被手动修改的生成文件
+		Force bool
+		// CacheDir 缓存目录，为空时使用默认缓存目录
+		CacheDir string
+		// NoCache 不使用缓存
+		NoCache bool
+		// Watch 监听配置变化并重新生成
+		Watch bool
+		// WithDeps 一并生成依赖项目的包
+		WithDeps bool
 	}
 )
 
@@ -18,3 +42,73 @@ func Examples(values ...string) string {
 	}
 	return strings.Join(values, "\n")
 }
+
+// OutputFlags 添加--check、--diff、--force、--with-deps和缓存参数
+func OutputFlags(cmd *cobra.Command, cfg *Config) {
+	cmd.Flags().BoolVar(&cfg.Check, "check", false, "检查生成的文件是否过期，不写入文件，过期时以状态码1退出")
+	cmd.Flags().BoolVar(&cfg.Diff, "diff", false, "输出与已有文件的差异，不写入文件，存在差异时以状态码1退出")
+	cmd.Flags().BoolVar(&cfg.Force, "force", false, "覆盖被手动修改的生成文件，删除自定义区域中有代码的不再生成的文件")
+	cmd.Flags().StringVar(&cfg.CacheDir, "cache-dir", "", "解析和渲染结果的缓存目录，默认为用户缓存目录下的windranger")
+	cmd.Flags().BoolVar(&cfg.NoCache, "no-cache", false, "不使用缓存")
+	cmd.Flags().BoolVar(&cfg.WithDeps, "with-deps", false, "一并生成依赖项目的包，默认只供引用")
+}
+
+// Select 指定--with-deps时将依赖项目的包视为本项目的包，使生成器一并生成
+func (c Config) Select(packages []*parser.Package) []*parser.Package {
+	if c.WithDeps {
+		for _, pack := range packages {
+			pack.Module = ""
+		}
+	}
+	return packages
+}
+
+// OpenCache 打开缓存，指定--no-cache时返回nil
+func (c Config) OpenCache() *cache.Cache {
+	if c.NoCache {
+		return nil
+	}
+	cache, err := cache.Open(c.CacheDir)
+	if err != nil {
+		panic(err)
+	}
+	return cache
+}
+
+// Emit 写入生成的文件并更新输出目录中的生成清单；指定--check或--diff时与磁盘比较，存在差异时以状态码1退出
+//
+// generator区分写入同一输出目录的多个生成器，schema为模型哈希
+func Emit(set *output.Set, cfg Config, generator string, schema string) {
+	if !cfg.Check && !cfg.Diff {
+		if err := Save(set, cfg, generator, schema); err != nil {
+			panic(err)
+		}
+		return
+	}
+	set.Track(cfg.Out, generator, version.Get(), schema)
+	changes, err := set.Check()
+	if err != nil {
+		panic(err)
+	}
+	for _, change := range changes {
+		if !cfg.Diff {
+			fmt.Fprintln(os.Stderr, change)
+			continue
+		}
+		diff, err := change.Diff()
+		if err != nil {
+			panic(err)
+		}
+		fmt.Print(diff)
+	}
+	if len(changes) != 0 {
+		os.Exit(1)
+	}
+}
+
+// Save 写入生成的文件并更新输出目录中的生成清单
+func Save(set *output.Set, cfg Config, generator string, schema string) error {
+	set.Track(cfg.Out, generator, version.Get(), schema)
+	set.Force = cfg.Force
+	return set.Write()
+}
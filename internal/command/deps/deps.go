@@ -0,0 +1,117 @@
+package deps
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/wzyjerry/windranger/internal/command"
+	"github.com/wzyjerry/windranger/internal/deps"
+)
+
+// Deps 管理windranger.yaml中声明的依赖项目
+func Deps() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "deps",
+		Short: "管理依赖的windranger项目",
+	}
+	cmd.AddCommand(list(), update(), verify())
+	return cmd
+}
+
+// short 缩短提交哈希
+func short(commit string) string {
+	if len(commit) > 12 {
+		return commit[:12]
+	}
+	return commit
+}
+
+// report 输出依赖状态，返回是否存在问题
+func report(statuses []*deps.Status) bool {
+	var failed bool
+	writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	for _, status := range statuses {
+		state := "ok"
+		if status.Problem != "" {
+			state = status.Problem
+			failed = true
+		}
+		commit := short(status.Commit)
+		if commit == "" {
+			commit = "-"
+		}
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\n", status.Name, status.Source, commit, state)
+	}
+	writer.Flush()
+	return failed
+}
+
+func list() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list profile",
+		Short: "列出依赖、远程或固定了sha256的资源、锁定的提交及状态",
+		Example: command.Examples(
+			"windranger deps list model",
+		),
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			statuses, err := deps.Check(args[0])
+			if err != nil {
+				panic(err)
+			}
+			report(statuses)
+		},
+	}
+}
+
+func update() *cobra.Command {
+	var opts deps.Options
+	cmd := &cobra.Command{
+		Use:   "update profile [name...]",
+		Short: "下载git依赖和远程资源并更新windranger.lock",
+		Long:  "不指定依赖名时按windranger.lock下载，只解析新增或声明变更的依赖；指定依赖名时重新解析其version，用于跟进分支或tag的变化。下载的内容必须与windranger.yaml中固定的sha256一致，--update-hashes改为更新sha256",
+		Example: command.Examples(
+			"windranger deps update model",
+			"windranger deps update model base",
+			"windranger deps update model --update-hashes",
+		),
+		Args: cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			opts.Names = args[1:]
+			if err := deps.Update(args[0], opts); err != nil {
+				panic(err)
+			}
+			statuses, err := deps.Check(args[0])
+			if err != nil {
+				panic(err)
+			}
+			if report(statuses) {
+				os.Exit(1)
+			}
+		},
+	}
+	cmd.Flags().BoolVar(&opts.UpdateHashes, "update-hashes", false, "审核变更后，用下载的内容更新windranger.yaml中的sha256")
+	return cmd
+}
+
+func verify() *cobra.Command {
+	return &cobra.Command{
+		Use:   "verify profile",
+		Short: "离线检查依赖是否已下载且与windranger.lock及固定的sha256一致",
+		Example: command.Examples(
+			"windranger deps verify model",
+		),
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			statuses, err := deps.Check(args[0])
+			if err != nil {
+				panic(err)
+			}
+			if report(statuses) {
+				os.Exit(1)
+			}
+		},
+	}
+}
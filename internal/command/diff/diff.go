@@ -0,0 +1,42 @@
+package diff
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/wzyjerry/windranger/internal/command"
+	"github.com/wzyjerry/windranger/internal/diff"
+)
+
+// Diff 比较两个版本的模型，存在破坏性变更时以非零状态退出
+func Diff() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "diff [flags] old new",
+		Short: "比较两个版本的模型并标记破坏性变更",
+		Example: command.Examples(
+			"windranger diff model.old model",
+			"windranger diff HEAD~1:model model",
+			"windranger diff v1.0.0:model HEAD:model",
+		),
+		Args: cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			old, errs := command.LoadPackages(args[0])
+			if errs != nil {
+				panic(errs[0])
+			}
+			new, errs := command.LoadPackages(args[1])
+			if errs != nil {
+				panic(errs[0])
+			}
+			changes := diff.Compare(old, new)
+			for _, change := range changes {
+				fmt.Println(change)
+			}
+			if diff.HasBreaking(changes) {
+				os.Exit(1)
+			}
+		},
+	}
+	return cmd
+}
@@ -0,0 +1,100 @@
+package format
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/wzyjerry/windranger/internal/command"
+	"github.com/wzyjerry/windranger/internal/format"
+	"github.com/wzyjerry/windranger/internal/parser"
+)
+
+// Config 格式化配置
+type Config struct {
+	format.Options
+	// Write 写回文件
+	Write bool
+	// Check 仅检查，存在未格式化的文件时以非零状态退出
+	Check bool
+}
+
+// files 展开参数，配置目录展开为其中的资源文件
+func files(args []string) ([]string, error) {
+	result := make([]string, 0, len(args))
+	for _, arg := range args {
+		info, err := os.Stat(arg)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			result = append(result, arg)
+			continue
+		}
+		resources, err := parser.Resources(arg)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, resources...)
+	}
+	return result, nil
+}
+
+// Fmt 将模型yaml格式化为规范形式
+func Fmt() *cobra.Command {
+	cfg := Config{
+		Options: *format.DefaultOptions(),
+	}
+	cmd := &cobra.Command{
+		Use:   "fmt [flags] path...",
+		Short: "将模型yaml格式化为规范形式",
+		Example: command.Examples(
+			"windranger fmt -w model",
+			"windranger fmt --check model",
+			"windranger fmt --enum-style flow --sort model/demo.yaml",
+		),
+		Args: cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			paths, err := files(args)
+			if err != nil {
+				panic(err)
+			}
+			unformatted := false
+			for _, name := range paths {
+				content, err := os.ReadFile(name)
+				if err != nil {
+					panic(err)
+				}
+				formatted, err := format.Format(content, &cfg.Options)
+				if err != nil {
+					panic(fmt.Errorf("%s: %w", name, err))
+				}
+				switch {
+				case cfg.Check:
+					if !bytes.Equal(content, formatted) {
+						fmt.Println(name)
+						unformatted = true
+					}
+				case cfg.Write:
+					if !bytes.Equal(content, formatted) {
+						if err := os.WriteFile(name, formatted, os.ModePerm); err != nil {
+							panic(err)
+						}
+					}
+				default:
+					os.Stdout.Write(formatted)
+				}
+			}
+			if unformatted {
+				os.Exit(1)
+			}
+		},
+	}
+	cmd.Flags().BoolVarP(&cfg.Write, "write", "w", false, "写回文件")
+	cmd.Flags().BoolVar(&cfg.Check, "check", false, "仅列出未格式化的文件，存在时以非零状态退出")
+	cmd.Flags().IntVar(&cfg.Indent, "indent", cfg.Indent, "缩进空格数")
+	cmd.Flags().StringVar(&cfg.EnumStyle, "enum-style", cfg.EnumStyle, "枚举风格: keep, block, flow")
+	cmd.Flags().BoolVar(&cfg.SortFields, "sort", false, "按主键优先、字典序排列字段")
+	return cmd
+}
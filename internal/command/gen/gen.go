@@ -0,0 +1,77 @@
+package gen
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/wzyjerry/windranger/internal/command"
+	"github.com/wzyjerry/windranger/internal/generator/gogo"
+	"github.com/wzyjerry/windranger/internal/ir"
+	"github.com/wzyjerry/windranger/internal/lint"
+	"github.com/wzyjerry/windranger/internal/plugin"
+)
+
+// Config 插件生成配置
+type Config struct {
+	command.Config
+	// Plugin 插件名或路径
+	Plugin string
+	// Parameters 插件参数
+	Parameters map[string]string
+}
+
+// Gen 使用外部插件生成文件
+func Gen() *cobra.Command {
+	var cfg Config
+	cmd := &cobra.Command{
+		Use:   "gen [flags] profile",
+		Short: "使用外部插件生成文件",
+		Example: command.Examples(
+			"windranger gen --plugin=foo model --out gen",
+			"windranger gen --plugin=./bin/windranger-gen-foo --param lang=ts,style=class model",
+		),
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if cfg.Plugin == "" {
+				panic(fmt.Errorf("缺少插件: --plugin"))
+			}
+			packages, errs := command.Load(args[0], cfg.OpenCache())
+			if errs != nil {
+				panic(errs[0])
+			}
+			schema := ir.Hash(packages)
+			packages = cfg.Select(packages)
+			packages, errs = gogo.Link(packages)
+			if errs != nil {
+				panic(errs[0])
+			}
+			response, err := plugin.Run(cfg.Plugin, packages, "go", cfg.Parameters)
+			if err != nil {
+				panic(err)
+			}
+			diagnostics, err := response.Report(cfg.Plugin)
+			if err != nil {
+				panic(err)
+			}
+			for _, diagnostic := range diagnostics {
+				fmt.Fprintln(os.Stderr, diagnostic)
+			}
+			// 插件报告错误时不写入任何文件
+			if lint.HasError(diagnostics) {
+				os.Exit(1)
+			}
+			set, err := response.Render(cfg.Out)
+			if err != nil {
+				panic(err)
+			}
+			command.Emit(set, cfg.Config, "gen:"+filepath.Base(cfg.Plugin), schema)
+		},
+	}
+	cmd.Flags().StringVar(&cfg.Plugin, "plugin", "", "插件名或路径，名称foo在PATH中查找windranger-gen-foo，包含路径分隔符时为可执行文件路径")
+	cmd.Flags().StringToStringVar(&cfg.Parameters, "param", nil, "插件参数，例如--param lang=ts,style=class")
+	cmd.Flags().StringVar(&cfg.Out, "out", ".", "生成根目录")
+	command.OutputFlags(cmd, &cfg.Config)
+	return cmd
+}
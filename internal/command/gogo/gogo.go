@@ -1,36 +1,60 @@
 package gogo
 
 import (
+	"fmt"
+	"os"
+
 	"github.com/spf13/cobra"
 	"github.com/wzyjerry/windranger/internal/command"
 	"github.com/wzyjerry/windranger/internal/generator/gogo"
-	"github.com/wzyjerry/windranger/internal/parser"
+	"github.com/wzyjerry/windranger/internal/ir"
+	"github.com/wzyjerry/windranger/internal/lint"
+	"github.com/wzyjerry/windranger/internal/output"
 )
 
 // Gogo 根据配置文件生成go文件
 func Gogo() *cobra.Command {
-	var cfg command.Config
+	var (
+		cfg     command.Config
+		imports map[string]string
+	)
 	cmd := &cobra.Command{
 		Use:   "gogo [flags] profile",
 		Short: "根据配置文件生成go文件",
 		Example: command.Examples(
 			"windranger gogo model --out model",
 			"windranger gogo http://example.com/model.git --out model",
+			"windranger gogo model --out model --check",
+			"windranger gogo model --out model --watch",
+			"windranger gogo - --out model < model.yaml",
+			"windranger gogo model --out model --dep-import base=github.com/example/base/model",
 		),
 		Args: cobra.ExactArgs(1),
 		Run: func(cmd *cobra.Command, args []string) {
-			p := parser.NewParser()
-			p.AddYamlPath(args[0])
-			packages, errs := p.Parse()
-			if errs != nil {
-				panic(errs[0])
-			}
-			if err := gogo.Generate(packages, cfg.Out); err != nil {
-				panic(err)
-			}
+			c := cfg.OpenCache()
+			command.Generate(args[0], cfg, "gogo", func() (*output.Set, string, []error) {
+				packages, errs := command.Load(args[0], c)
+				if errs != nil {
+					return nil, "", errs
+				}
+				schema := ir.Hash(packages)
+				packages = cfg.Select(packages)
+				for _, warning := range lint.CheckDeprecated(packages) {
+					fmt.Fprintln(os.Stderr, warning)
+				}
+				set, err := gogo.Render(packages, cfg.Out, c, imports)
+				if err != nil {
+					return nil, "", []error{err}
+				}
+				return set, schema, nil
+			})
 		},
 	}
 	// 生成根目录
 	cmd.Flags().StringVar(&cfg.Out, "out", ".", "生成根目录")
+	// 依赖项目生成的go包
+	cmd.Flags().StringToStringVar(&imports, "dep-import", nil, "依赖项目生成的go包导入路径，格式为<依赖名>=<导入路径>，可以重复指定")
+	command.OutputFlags(cmd, &cfg)
+	command.WatchFlag(cmd, &cfg)
 	return cmd
 }
@@ -0,0 +1,68 @@
+package ir
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/wzyjerry/windranger/internal/command"
+	"github.com/wzyjerry/windranger/internal/generator/gogo"
+	"github.com/wzyjerry/windranger/internal/ir"
+)
+
+// Config IR输出配置
+type Config struct {
+	// Stage 输出阶段: parsed或linked
+	Stage string
+	// Out 输出文件，为空时输出到标准输出
+	Out string
+}
+
+// IR 输出解析或链接后的中间表示
+func IR() *cobra.Command {
+	var cfg Config
+	cmd := &cobra.Command{
+		Use:   "ir [flags] profile",
+		Short: "以JSON格式输出模型的中间表示",
+		Example: command.Examples(
+			"windranger ir model",
+			"windranger ir --stage parsed model --out model.ir.json",
+			"windranger ir HEAD~1:model",
+		),
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			packages, errs := command.LoadPackages(args[0])
+			if errs != nil {
+				panic(errs[0])
+			}
+			var linker string
+			switch cfg.Stage {
+			case ir.StageParsed:
+			case ir.StageLinked:
+				packages, errs = gogo.Link(packages)
+				if errs != nil {
+					panic(errs[0])
+				}
+				linker = "go"
+			default:
+				panic(fmt.Errorf("未知IR阶段: %s", cfg.Stage))
+			}
+			var w io.Writer = os.Stdout
+			if cfg.Out != "" {
+				file, err := os.Create(cfg.Out)
+				if err != nil {
+					panic(err)
+				}
+				defer file.Close()
+				w = file
+			}
+			if err := ir.Encode(w, packages, linker); err != nil {
+				panic(err)
+			}
+		},
+	}
+	cmd.Flags().StringVar(&cfg.Stage, "stage", ir.StageLinked, "输出阶段: parsed(链接前)或linked(按Go后端链接后)")
+	cmd.Flags().StringVar(&cfg.Out, "out", "", "输出文件，默认输出到标准输出")
+	return cmd
+}
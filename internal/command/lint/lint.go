@@ -0,0 +1,49 @@
+package lint
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/wzyjerry/windranger/internal/command"
+	"github.com/wzyjerry/windranger/internal/lint"
+)
+
+// Lint 按windranger.yaml中的配置检查模型，存在错误时以非零状态退出
+func Lint() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "lint [flags] profile",
+		Short: "检查模型是否符合最佳实践",
+		Example: command.Examples(
+			"windranger lint model",
+			"windranger lint HEAD:model",
+			"windranger lint - < model.yaml",
+		),
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			packages, errs := command.LoadPackages(args[0])
+			if errs != nil {
+				panic(errs[0])
+			}
+			profile, err := command.LoadProfile(args[0])
+			if err != nil {
+				panic(err)
+			}
+			cfg, err := lint.ParseConfig(profile)
+			if err != nil {
+				panic(err)
+			}
+			diagnostics, err := lint.Run(packages, cfg)
+			if err != nil {
+				panic(err)
+			}
+			for _, diagnostic := range diagnostics {
+				fmt.Println(diagnostic)
+			}
+			if lint.HasError(diagnostics) {
+				os.Exit(1)
+			}
+		},
+	}
+	return cmd
+}
@@ -0,0 +1,27 @@
+package lsp
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/wzyjerry/windranger/internal/command"
+	"github.com/wzyjerry/windranger/internal/lsp"
+)
+
+// Lsp 启动基于标准输入输出的语言服务
+func Lsp() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "lsp",
+		Short: "启动基于标准输入输出的语言服务",
+		Example: command.Examples(
+			"windranger lsp",
+		),
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := lsp.NewServer(os.Stdin, os.Stdout).Run(); err != nil {
+				panic(err)
+			}
+		},
+	}
+	return cmd
+}
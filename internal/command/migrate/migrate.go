@@ -0,0 +1,61 @@
+package migrate
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/wzyjerry/windranger/internal/command"
+	generator "github.com/wzyjerry/windranger/internal/generator/migrate"
+	"github.com/wzyjerry/windranger/internal/migrate"
+)
+
+// Config 迁移配置
+type Config struct {
+	command.Config
+	// Name 迁移名，追加在时间戳之后
+	Name string
+	// Format 输出格式
+	Format string
+}
+
+// Migrate 比较两个版本的模型并生成数据迁移脚本
+func Migrate() *cobra.Command {
+	var cfg Config
+	cmd := &cobra.Command{
+		Use:   "migrate [flags] old new",
+		Short: "比较两个版本的模型并生成数据迁移脚本",
+		Example: command.Examples(
+			"windranger migrate HEAD~1:model model --name rename_title --out migrations",
+			"windranger migrate model.old model --format go --out migrations",
+		),
+		Args: cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			old, errs := command.LoadPackages(args[0])
+			if errs != nil {
+				panic(errs[0])
+			}
+			new, errs := command.LoadPackages(args[1])
+			if errs != nil {
+				panic(errs[0])
+			}
+			id := time.Now().Format("20060102150405")
+			if cfg.Name != "" {
+				id += "_" + cfg.Name
+			}
+			plan := migrate.Generate(id, old, new)
+			for _, manual := range plan.Manual {
+				fmt.Fprintln(os.Stderr, "需要手动迁移:", manual)
+			}
+			if err := generator.Generate(plan, cfg.Out, cfg.Format); err != nil {
+				panic(err)
+			}
+		},
+	}
+	// 生成根目录
+	cmd.Flags().StringVar(&cfg.Out, "out", ".", "生成根目录")
+	cmd.Flags().StringVar(&cfg.Name, "name", "", "迁移名")
+	cmd.Flags().StringVar(&cfg.Format, "format", generator.FormatMongosh, "输出格式: mongosh, go")
+	return cmd
+}
@@ -0,0 +1,40 @@
+package mongo
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/wzyjerry/windranger/internal/command"
+	"github.com/wzyjerry/windranger/internal/generator/mongo"
+	"github.com/wzyjerry/windranger/internal/ir"
+)
+
+// MongoInit 根据配置文件生成mongosh集合初始化脚本
+func MongoInit() *cobra.Command {
+	var cfg command.Config
+	cmd := &cobra.Command{
+		Use:   "mongo-init [flags] profile",
+		Short: "根据配置文件生成mongosh集合初始化脚本",
+		Example: command.Examples(
+			"windranger mongo-init model --out script",
+			"windranger mongo-init model --out script --diff",
+		),
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			c := cfg.OpenCache()
+			packages, errs := command.Load(args[0], c)
+			if errs != nil {
+				panic(errs[0])
+			}
+			schema := ir.Hash(packages)
+			packages = cfg.Select(packages)
+			set, err := mongo.Render(packages, cfg.Out, c)
+			if err != nil {
+				panic(err)
+			}
+			command.Emit(set, cfg, "mongo-init", schema)
+		},
+	}
+	// 生成根目录
+	cmd.Flags().StringVar(&cfg.Out, "out", ".", "生成根目录")
+	command.OutputFlags(cmd, &cfg)
+	return cmd
+}
@@ -0,0 +1,138 @@
+package command
+
+import (
+	"archive/tar"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/wzyjerry/windranger/internal/cache"
+	"github.com/wzyjerry/windranger/internal/parser"
+)
+
+// Stdin 从标准输入读取模型的source
+const Stdin = "-"
+
+// LoadPackages 从配置目录、标准输入或git版本加载模型
+//
+// source为目录时直接解析；为'-'时从标准输入读取以'---'分隔的多个模型；
+// 否则按<rev>:<path>解析为当前git仓库中的版本，path相对于仓库根目录，省略时为仓库根目录
+func LoadPackages(source string) ([]*parser.Package, []error) {
+	return Load(source, nil)
+}
+
+// Load 与LoadPackages相同，c不为nil时使用文档解析缓存
+func Load(source string, c *cache.Cache) ([]*parser.Package, []error) {
+	p := parser.NewParser()
+	if c != nil {
+		p.SetCache(c.Documents())
+	}
+	if source == Stdin {
+		content, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return nil, []error{err}
+		}
+		return p.AddYamlStream("<stdin>", content).Parse()
+	}
+	if info, err := os.Stat(source); err == nil && info.IsDir() {
+		return p.AddYamlPath(source).Parse()
+	}
+	rev, sub, ok := strings.Cut(source, ":")
+	if !ok {
+		return nil, []error{fmt.Errorf("既不是目录也不是git版本: %s", source)}
+	}
+	root, err := exportRevision(rev, sub)
+	if err != nil {
+		return nil, []error{err}
+	}
+	defer os.RemoveAll(root)
+	return p.AddYamlPath(filepath.Join(root, sub)).Parse()
+}
+
+// LoadProfile 从与Load相同的source读取windranger.yaml，标准输入没有配置文件，返回nil
+func LoadProfile(source string) ([]byte, error) {
+	if source == Stdin {
+		return nil, nil
+	}
+	if info, err := os.Stat(source); err == nil && info.IsDir() {
+		return os.ReadFile(filepath.Join(source, parser.Profile))
+	}
+	rev, sub, ok := strings.Cut(source, ":")
+	if !ok {
+		return nil, fmt.Errorf("既不是目录也不是git版本: %s", source)
+	}
+	top, err := exec.Command("git", "rev-parse", "--show-toplevel").Output()
+	if err != nil {
+		return nil, fmt.Errorf("当前目录不是git仓库: %w", err)
+	}
+	var stderr bytes.Buffer
+	cmd := exec.Command("git", "show", rev+":"+path.Join(sub, parser.Profile))
+	cmd.Dir = strings.TrimSpace(string(top))
+	cmd.Stderr = &stderr
+	content, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("读取git版本%s中的%s失败: %s", rev, parser.Profile, strings.TrimSpace(stderr.String()))
+	}
+	return content, nil
+}
+
+// exportRevision 将git版本中的目录导出到临时目录
+func exportRevision(rev string, sub string) (string, error) {
+	top, err := exec.Command("git", "rev-parse", "--show-toplevel").Output()
+	if err != nil {
+		return "", fmt.Errorf("当前目录不是git仓库: %w", err)
+	}
+	if sub == "" {
+		sub = "."
+	}
+	var stderr bytes.Buffer
+	cmd := exec.Command("git", "archive", "--format=tar", rev, "--", sub)
+	cmd.Dir = strings.TrimSpace(string(top))
+	cmd.Stderr = &stderr
+	archive, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("导出git版本%s失败: %s", rev, strings.TrimSpace(stderr.String()))
+	}
+	root, err := os.MkdirTemp("", "windranger-")
+	if err != nil {
+		return "", err
+	}
+	reader := tar.NewReader(bytes.NewReader(archive))
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			os.RemoveAll(root)
+			return "", err
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		target := filepath.Join(root, filepath.FromSlash(header.Name))
+		if !strings.HasPrefix(target, root+string(filepath.Separator)) {
+			os.RemoveAll(root)
+			return "", fmt.Errorf("非法路径: %s", header.Name)
+		}
+		if err := os.MkdirAll(filepath.Dir(target), os.ModePerm); err != nil {
+			os.RemoveAll(root)
+			return "", err
+		}
+		content, err := io.ReadAll(reader)
+		if err != nil {
+			os.RemoveAll(root)
+			return "", err
+		}
+		if err := os.WriteFile(target, content, os.ModePerm); err != nil {
+			os.RemoveAll(root)
+			return "", err
+		}
+	}
+	return root, nil
+}
@@ -0,0 +1,60 @@
+package template
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/wzyjerry/windranger/internal/command"
+	"github.com/wzyjerry/windranger/internal/generator/custom"
+	"github.com/wzyjerry/windranger/internal/ir"
+	"github.com/wzyjerry/windranger/internal/lint"
+)
+
+// Config 用户模板生成配置
+type Config struct {
+	command.Config
+	// Dir 模板目录
+	Dir string
+	// Parameters 模板参数
+	Parameters map[string]string
+}
+
+// Template 根据用户模板生成文件
+func Template() *cobra.Command {
+	var cfg Config
+	cmd := &cobra.Command{
+		Use:   "template [flags] profile",
+		Short: "根据用户模板生成文件",
+		Example: command.Examples(
+			"windranger template --dir templates/ts model --out web/src/model",
+			"windranger template --dir templates/proto --param package=demo.v1 model",
+		),
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if cfg.Dir == "" {
+				panic(fmt.Errorf("缺少模板目录: --dir"))
+			}
+			packages, errs := command.Load(args[0], cfg.OpenCache())
+			if errs != nil {
+				panic(errs[0])
+			}
+			schema := ir.Hash(packages)
+			packages = cfg.Select(packages)
+			for _, warning := range lint.CheckDeprecated(packages) {
+				fmt.Fprintln(os.Stderr, warning)
+			}
+			set, err := custom.Render(os.DirFS(cfg.Dir), packages, cfg.Parameters, cfg.Out)
+			if err != nil {
+				panic(err)
+			}
+			command.Emit(set, cfg.Config, "template:"+filepath.Base(cfg.Dir), schema)
+		},
+	}
+	cmd.Flags().StringVar(&cfg.Dir, "dir", "", "模板目录，包含template.yaml清单和*.tmpl模板")
+	cmd.Flags().StringToStringVar(&cfg.Parameters, "param", nil, "模板参数，在模板中通过.Parameters访问")
+	cmd.Flags().StringVar(&cfg.Out, "out", ".", "生成根目录")
+	command.OutputFlags(cmd, &cfg.Config)
+	return cmd
+}
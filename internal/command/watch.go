@@ -0,0 +1,81 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/wzyjerry/windranger/internal/output"
+	"github.com/wzyjerry/windranger/internal/watch"
+)
+
+// Render 解析配置并渲染生成的文件，返回文件集合和模型哈希
+type Render func() (*output.Set, string, []error)
+
+// WatchFlag 添加--watch
+func WatchFlag(cmd *cobra.Command, cfg *Config) {
+	cmd.Flags().BoolVar(&cfg.Watch, "watch", false, "监听配置目录、windranger.yaml及其中的资源文件，修改后重新生成，出错时不退出")
+}
+
+// Generate 执行一次生成；指定--watch时持续监听source并在修改后重新生成，直到收到中断信号
+func Generate(source string, cfg Config, generator string, render Render) {
+	if !cfg.Watch {
+		set, schema, errs := render()
+		if errs != nil {
+			panic(errs[0])
+		}
+		Emit(set, cfg, generator, schema)
+		return
+	}
+	if cfg.Check || cfg.Diff {
+		panic(fmt.Errorf("--watch不能与--check或--diff同时使用"))
+	}
+	if info, err := os.Stat(source); err != nil || !info.IsDir() {
+		panic(fmt.Errorf("--watch只支持配置目录: %s", source))
+	}
+	// 最近一次生成的文件，生成目录可能位于配置目录下，生成结果不应再次触发生成
+	out := watch.Absolute(cfg.Out)
+	generated := make(map[string]struct{})
+	run := func() {
+		set, schema, errs := render()
+		if errs == nil {
+			generated = make(map[string]struct{}, len(set.Files()))
+			for _, file := range set.Files() {
+				generated[watch.Absolute(file.Path)] = struct{}{}
+			}
+			if err := Save(set, cfg, generator, schema); err != nil {
+				errs = []error{err}
+			}
+		}
+		// 报告全部错误后继续监听
+		for _, err := range errs {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		if errs == nil {
+			fmt.Fprintf(os.Stderr, "%s 生成完成\n", time.Now().Format("15:04:05"))
+		}
+	}
+	run()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	fmt.Fprintf(os.Stderr, "正在监听%s，按Ctrl+C退出\n", source)
+	opts := watch.DefaultOptions
+	opts.Exclude = func(name string) bool {
+		// 生成目录与配置目录相同时只排除生成的文件
+		if name == out || name == filepath.Join(out, output.ManifestName) {
+			return true
+		}
+		_, ok := generated[name]
+		return ok
+	}
+	watch.Watch(ctx, source, opts, func(changed []string) {
+		for _, name := range changed {
+			fmt.Fprintf(os.Stderr, "修改: %s\n", name)
+		}
+		run()
+	})
+}
@@ -0,0 +1,336 @@
+package deps
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/wzyjerry/windranger/internal/parser"
+	"gopkg.in/yaml.v3"
+)
+
+// LockName 配置目录中记录git依赖提交的锁文件名
+const LockName = "windranger.lock"
+
+type (
+	// Lock 锁文件
+	Lock struct {
+		Dependencies []*Locked `yaml:"dependencies"`
+	}
+	// Locked git依赖下载时的声明及解析出的提交
+	Locked struct {
+		Name    string `yaml:"name"`
+		Git     string `yaml:"git"`
+		Version string `yaml:"version"`
+		Dir     string `yaml:"dir,omitempty"`
+		Commit  string `yaml:"commit"`
+	}
+	// Status 依赖或远程、固定了sha256的资源的状态
+	Status struct {
+		// Name 依赖名或资源文件路径
+		Name string
+		// Source 依赖来源或资源声明
+		Source string
+		// Commit 锁定的提交，本地依赖和资源为空
+		Commit string
+		// Problem 不可用、与锁文件或sha256不一致的原因，为空表示正常
+		Problem string
+	}
+	// Options 更新参数
+	Options struct {
+		// Names 重新解析version的依赖，为空时按锁文件下载
+		Names []string
+		// UpdateHashes 用下载的内容更新windranger.yaml中的sha256，而不是校验
+		UpdateHashes bool
+	}
+)
+
+// ReadLock 读取配置目录中的锁文件，不存在时返回空锁文件
+func ReadLock(root string) (*Lock, error) {
+	lock := new(Lock)
+	content, err := os.ReadFile(filepath.Join(root, LockName))
+	if errors.Is(err, os.ErrNotExist) {
+		return lock, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(content, lock); err != nil {
+		return nil, fmt.Errorf("%s格式错误: %w", LockName, err)
+	}
+	return lock, nil
+}
+
+// Write 将锁文件写入配置目录
+func (l *Lock) Write(root string) error {
+	var buffer bytes.Buffer
+	encoder := yaml.NewEncoder(&buffer)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(l); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(root, LockName), buffer.Bytes(), os.ModePerm)
+}
+
+// find 查找依赖的锁定记录
+func (l *Lock) find(name string) *Locked {
+	for _, locked := range l.Dependencies {
+		if locked.Name == name {
+			return locked
+		}
+	}
+	return nil
+}
+
+// matches 判断锁定记录是否与当前声明一致
+func (l *Locked) matches(dep *parser.Dependency) bool {
+	return l.Git == dep.Git && l.Version == dep.Version && l.Dir == dep.Dir
+}
+
+// git 在dir中执行git命令，返回去除首尾空白的标准输出
+func git(dir string, args ...string) (string, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %s", strings.Join(args, " "), strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// vendor git依赖的下载目录
+func vendor(root string, dep *parser.Dependency) string {
+	return filepath.Join(root, filepath.FromSlash(parser.VendorDir), dep.Name)
+}
+
+// Check 离线检查每个依赖是否可用且与锁文件一致，以及依赖和资源是否与固定的sha256一致
+func Check(root string) ([]*Status, error) {
+	deps, err := parser.Dependencies(root)
+	if err != nil {
+		return nil, err
+	}
+	lock, err := ReadLock(root)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*Status, len(deps))
+	for i, dep := range deps {
+		result[i] = check(root, dep, lock)
+	}
+	for _, locked := range lock.Dependencies {
+		if !declared(deps, locked.Name) {
+			dep := &parser.Dependency{Name: locked.Name, Git: locked.Git, Version: locked.Version, Dir: locked.Dir}
+			result = append(result, &Status{
+				Name:    dep.Name,
+				Source:  dep.String(),
+				Commit:  locked.Commit,
+				Problem: "已不再声明，请执行windranger deps update",
+			})
+		}
+	}
+	pins, err := parser.Pins(root)
+	if err != nil {
+		return nil, err
+	}
+	for _, pin := range pins {
+		result = append(result, checkPin(root, pin))
+	}
+	return result, nil
+}
+
+// declared 判断依赖是否仍在windranger.yaml中声明
+func declared(deps []*parser.Dependency, name string) bool {
+	for _, dep := range deps {
+		if dep.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// check 检查单个依赖
+func check(root string, dep *parser.Dependency, lock *Lock) *Status {
+	status := &Status{Name: dep.Name, Source: dep.String()}
+	profile := filepath.Join(filepath.FromSlash(dep.Root(root)), parser.Profile)
+	if dep.Git == "" {
+		if _, err := os.Stat(profile); err != nil {
+			status.Problem = fmt.Sprintf("缺少%s", profile)
+			return status
+		}
+		status.Problem = checkDigest(root, dep)
+		return status
+	}
+	locked := lock.find(dep.Name)
+	if locked == nil || !locked.matches(dep) {
+		status.Problem = "未锁定或声明已变更，请执行windranger deps update"
+		return status
+	}
+	status.Commit = locked.Commit
+	dir := vendor(root, dep)
+	if _, err := os.Stat(filepath.Join(dir, ".git")); err != nil {
+		status.Problem = "尚未下载，请执行windranger deps update"
+		return status
+	}
+	head, err := git(dir, "rev-parse", "HEAD")
+	if err != nil {
+		status.Problem = err.Error()
+		return status
+	}
+	if head != locked.Commit {
+		status.Problem = fmt.Sprintf("下载的提交%s与锁定的提交不一致", head)
+		return status
+	}
+	changes, err := git(dir, "status", "--porcelain")
+	if err != nil {
+		status.Problem = err.Error()
+		return status
+	}
+	if changes != "" {
+		status.Problem = "下载的文件被修改"
+		return status
+	}
+	if _, err := os.Stat(profile); err != nil {
+		status.Problem = fmt.Sprintf("缺少%s", profile)
+		return status
+	}
+	status.Problem = checkDigest(root, dep)
+	return status
+}
+
+// Update 下载git依赖和远程资源并更新锁文件，然后校验固定的sha256
+//
+// 未指定Names时按锁文件下载，只解析新增或声明变更的依赖；指定Names时重新解析这些依赖的version。
+// 不再声明的依赖从锁文件和下载目录中删除。指定UpdateHashes时不校验，
+// 而是将git依赖、远程资源及已固定的依赖和资源的sha256更新为下载的内容
+func Update(root string, opts Options) error {
+	deps, err := parser.Dependencies(root)
+	if err != nil {
+		return err
+	}
+	names := opts.Names
+	for _, name := range names {
+		if !declared(deps, name) {
+			return fmt.Errorf("未声明的依赖: %s", name)
+		}
+	}
+	lock, err := ReadLock(root)
+	if err != nil {
+		return err
+	}
+	updated := &Lock{
+		Dependencies: make([]*Locked, 0, len(deps)),
+	}
+	for _, dep := range deps {
+		if dep.Git == "" {
+			continue
+		}
+		locked := lock.find(dep.Name)
+		refresh := locked == nil || !locked.matches(dep)
+		for _, name := range names {
+			refresh = refresh || name == dep.Name
+		}
+		commit, err := fetch(root, dep, locked, refresh)
+		if err != nil {
+			return fmt.Errorf("依赖%s: %w", dep.Name, err)
+		}
+		updated.Dependencies = append(updated.Dependencies, &Locked{
+			Name:    dep.Name,
+			Git:     dep.Git,
+			Version: dep.Version,
+			Dir:     dep.Dir,
+			Commit:  commit,
+		})
+	}
+	for _, locked := range lock.Dependencies {
+		if !declared(deps, locked.Name) {
+			if err := os.RemoveAll(vendor(root, &parser.Dependency{Name: locked.Name})); err != nil {
+				return err
+			}
+		}
+	}
+	if err := updated.Write(root); err != nil {
+		return err
+	}
+	pins, err := parser.Pins(root)
+	if err != nil {
+		return err
+	}
+	for _, pin := range pins {
+		if pin.URL == "" {
+			continue
+		}
+		if err := download(pin, opts.UpdateHashes); err != nil {
+			return err
+		}
+	}
+	if opts.UpdateHashes {
+		return updateHashes(root, deps, pins)
+	}
+	return verify(root, deps, pins)
+}
+
+// fetch 下载依赖并检出提交，refresh为false时检出锁定的提交，否则重新解析version
+func fetch(root string, dep *parser.Dependency, locked *Locked, refresh bool) (string, error) {
+	dir := vendor(root, dep)
+	// 尚未下载或仓库地址变更时重新克隆
+	if origin(dir) != dep.Git {
+		if err := os.RemoveAll(dir); err != nil {
+			return "", err
+		}
+		if err := os.MkdirAll(filepath.Dir(dir), os.ModePerm); err != nil {
+			return "", err
+		}
+		if _, err := git(filepath.Dir(dir), "clone", "--quiet", "--no-checkout", dep.Git, dep.Name); err != nil {
+			return "", err
+		}
+	}
+	target := dep.Version
+	if !refresh {
+		target = locked.Commit
+	}
+	// 重新解析时需要远程最新的分支和tag，本地缺少锁定的提交时同样需要获取
+	if _, err := resolve(dir, target); refresh || err != nil {
+		if _, err := git(dir, "fetch", "--quiet", "--tags", "--force", "origin"); err != nil {
+			return "", err
+		}
+	}
+	commit, err := resolve(dir, target)
+	if err != nil {
+		return "", fmt.Errorf("找不到版本%s", target)
+	}
+	if _, err := git(dir, "checkout", "--quiet", "--force", "--detach", commit); err != nil {
+		return "", err
+	}
+	if _, err := git(dir, "clean", "--quiet", "-fdx"); err != nil {
+		return "", err
+	}
+	return commit, nil
+}
+
+// origin 下载目录的远程仓库地址，未下载时为空
+func origin(dir string) string {
+	// 避免在上级仓库中执行
+	if _, err := os.Stat(filepath.Join(dir, ".git")); err != nil {
+		return ""
+	}
+	url, err := git(dir, "remote", "get-url", "origin")
+	if err != nil {
+		return ""
+	}
+	return url
+}
+
+// resolve 将远程分支、tag或提交解析为提交
+func resolve(dir string, version string) (string, error) {
+	commit, err := git(dir, "rev-parse", "--verify", "--quiet", "origin/"+version+"^{commit}")
+	if err == nil {
+		return commit, nil
+	}
+	return git(dir, "rev-parse", "--verify", "--quiet", version+"^{commit}")
+}
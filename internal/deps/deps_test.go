@@ -0,0 +1,178 @@
+package deps
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/wzyjerry/windranger/internal/parser"
+)
+
+// write 写入文件并按需创建目录
+func write(t *testing.T, name string, content string) {
+	require.Nil(t, os.MkdirAll(filepath.Dir(name), os.ModePerm))
+	require.Nil(t, os.WriteFile(name, []byte(content), os.ModePerm))
+}
+
+// commit 提交仓库中的全部修改
+func commit(t *testing.T, dir string, args ...string) {
+	for _, command := range [][]string{
+		{"add", "-A"},
+		{"-c", "user.name=windranger", "-c", "user.email=windranger@example.com", "commit", "-q", "-m", "update"},
+	} {
+		cmd := exec.Command("git", command...)
+		cmd.Dir = dir
+		output, err := cmd.CombinedOutput()
+		require.Nil(t, err, string(output))
+	}
+	if len(args) != 0 {
+		_, err := git(dir, args...)
+		require.Nil(t, err)
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("缺少git")
+	}
+	validator := require.New(t)
+	dir := t.TempDir()
+	remote := filepath.Join(dir, "base")
+	_, err := git(dir, "init", "-q", "-b", "main", remote)
+	validator.Nil(err)
+	write(t, filepath.Join(remote, "model", "windranger.yaml"), "version: v1\nkind: Windranger\nresources:\n  - common.yaml\n")
+	write(t, filepath.Join(remote, "model", "common.yaml"), "version: v1\n")
+	commit(t, remote, "tag", "v1")
+	first, err := git(remote, "rev-parse", "HEAD")
+	validator.Nil(err)
+
+	root := filepath.Join(dir, "app")
+	write(t, filepath.Join(root, "windranger.yaml"), `version: v1
+kind: Windranger
+resources: []
+dependencies:
+  - name: base
+    git: `+remote+`
+    version: main
+    dir: model
+`)
+	statuses, err := Check(root)
+	validator.Nil(err)
+	validator.Equal("未锁定或声明已变更，请执行windranger deps update", statuses[0].Problem)
+
+	validator.Nil(Update(root, Options{}))
+	statuses, err = Check(root)
+	validator.Nil(err)
+	validator.Equal("", statuses[0].Problem)
+	validator.Equal(first, statuses[0].Commit)
+
+	// 不指定依赖名时保持锁定的提交
+	write(t, filepath.Join(remote, "model", "common.yaml"), "version: v2\n")
+	commit(t, remote)
+	second, err := git(remote, "rev-parse", "HEAD")
+	validator.Nil(err)
+	validator.Nil(Update(root, Options{}))
+	lock, err := ReadLock(root)
+	validator.Nil(err)
+	validator.Equal(first, lock.Dependencies[0].Commit)
+	validator.Nil(Update(root, Options{Names: []string{"base"}}))
+	lock, err = ReadLock(root)
+	validator.Nil(err)
+	validator.Equal(second, lock.Dependencies[0].Commit)
+
+	// 离线检查发现下载目录中的修改
+	vendored := filepath.Join(root, ".windranger", "deps", "base", "model", "common.yaml")
+	write(t, vendored, "version: v3\n")
+	statuses, err = Check(root)
+	validator.Nil(err)
+	validator.Equal("下载的文件被修改", statuses[0].Problem)
+	validator.Nil(Update(root, Options{}))
+	content, err := os.ReadFile(vendored)
+	validator.Nil(err)
+	validator.Equal("version: v2\n", string(content))
+
+	// 取消声明后删除
+	validator.EqualError(Update(root, Options{Names: []string{"missing"}}), "未声明的依赖: missing")
+	write(t, filepath.Join(root, "windranger.yaml"), "version: v1\nkind: Windranger\nresources: []\n")
+	statuses, err = Check(root)
+	validator.Nil(err)
+	validator.Equal("已不再声明，请执行windranger deps update", statuses[0].Problem)
+	validator.Nil(Update(root, Options{}))
+	_, err = os.Stat(filepath.Join(root, ".windranger", "deps", "base"))
+	validator.True(os.IsNotExist(err))
+	statuses, err = Check(root)
+	validator.Nil(err)
+	validator.Empty(statuses)
+}
+
+func TestUpdateHashes(t *testing.T) {
+	validator := require.New(t)
+	content := "version: v1\n"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(content))
+	}))
+	defer server.Close()
+	dir := t.TempDir()
+	write(t, filepath.Join(dir, "base", "windranger.yaml"), "version: v1\nkind: Windranger\nresources:\n  - common.yaml\n")
+	write(t, filepath.Join(dir, "base", "common.yaml"), "version: v1\n")
+	root := filepath.Join(dir, "app")
+	profile := filepath.Join(root, "windranger.yaml")
+	write(t, profile, `version: v1
+kind: Windranger
+resources:
+  - `+server.URL+`/common.yaml # 共享模型
+dependencies:
+  - name: base
+    path: ../base
+    sha256: `+strings.Repeat("0", 64)+`
+`)
+	statuses, err := Check(root)
+	validator.Nil(err)
+	validator.Len(statuses, 2)
+	validator.Equal("尚未下载，请执行windranger deps update", statuses[1].Problem)
+
+	// 远程资源未固定时直接下载，依赖内容与固定的哈希不一致
+	err = Update(root, Options{})
+	validator.NotNil(err)
+	validator.Contains(err.Error(), "依赖base的sha256不一致")
+	validator.Nil(Update(root, Options{UpdateHashes: true}))
+	digest, err := parser.Digest(filepath.Join(dir, "base"))
+	validator.Nil(err)
+	updated, err := os.ReadFile(profile)
+	validator.Nil(err)
+	validator.Equal(`version: v1
+kind: Windranger
+resources:
+  - url: `+server.URL+`/common.yaml # 共享模型
+    sha256: `+parser.Sum([]byte(content))+`
+dependencies:
+  - name: base
+    path: ../base
+    sha256: `+digest+`
+`, string(updated))
+	statuses, err = Check(root)
+	validator.Nil(err)
+	for _, status := range statuses {
+		validator.Equal("", status.Problem)
+	}
+
+	// 远程内容变更时不写入
+	content = "version: v2\n"
+	pins, err := parser.Pins(root)
+	validator.Nil(err)
+	validator.Nil(os.Remove(filepath.FromSlash(pins[0].Name)))
+	err = Update(root, Options{})
+	validator.NotNil(err)
+	validator.Contains(err.Error(), server.URL+"/common.yaml的sha256不一致")
+	_, err = os.Stat(filepath.FromSlash(pins[0].Name))
+	validator.True(os.IsNotExist(err))
+	validator.Nil(Update(root, Options{UpdateHashes: true}))
+	statuses, err = Check(root)
+	validator.Nil(err)
+	validator.Equal("", statuses[1].Problem)
+}
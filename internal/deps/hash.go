@@ -0,0 +1,261 @@
+package deps
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/wzyjerry/windranger/internal/parser"
+	"gopkg.in/yaml.v3"
+)
+
+// client 下载远程资源使用的客户端
+var client = &http.Client{Timeout: 30 * time.Second}
+
+// checkDigest 检查依赖内容是否与固定的sha256一致，返回问题
+func checkDigest(root string, dep *parser.Dependency) string {
+	if dep.SHA256 == "" {
+		return ""
+	}
+	digest, err := parser.Digest(dep.Root(root))
+	if err != nil {
+		return err.Error()
+	}
+	if digest != dep.SHA256 {
+		return fmt.Sprintf("内容与sha256不一致，实际为%s", digest)
+	}
+	return ""
+}
+
+// checkPin 离线检查远程资源是否已下载，以及内容是否与固定的sha256一致
+func checkPin(root string, pin *parser.Pin) *Status {
+	name := filepath.FromSlash(pin.Name)
+	status := &Status{Name: name, Source: pin.Entry}
+	if rel, err := filepath.Rel(root, name); err == nil {
+		status.Name = rel
+	}
+	content, err := os.ReadFile(name)
+	if err != nil {
+		if pin.URL != "" && errors.Is(err, os.ErrNotExist) {
+			status.Problem = "尚未下载，请执行windranger deps update"
+		} else {
+			status.Problem = err.Error()
+		}
+		return status
+	}
+	if got := parser.Sum(content); pin.SHA256 != "" && got != pin.SHA256 {
+		status.Problem = fmt.Sprintf("内容与sha256不一致，实际为%s", got)
+	}
+	return status
+}
+
+// download 下载远程资源，未指定force时内容必须与固定的sha256一致，已下载且一致时跳过
+func download(pin *parser.Pin, force bool) error {
+	name := filepath.FromSlash(pin.Name)
+	if content, err := os.ReadFile(name); err == nil && pin.SHA256 != "" && parser.Sum(content) == pin.SHA256 {
+		return nil
+	}
+	resp, err := client.Get(pin.URL)
+	if err != nil {
+		return fmt.Errorf("下载%s失败: %w", pin.URL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("下载%s失败: %s", pin.URL, resp.Status)
+	}
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("下载%s失败: %w", pin.URL, err)
+	}
+	if !force {
+		// 不一致的内容不写入，避免后续生成使用
+		if err := pin.Verify(content); err != nil {
+			return err
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(name), os.ModePerm); err != nil {
+		return err
+	}
+	return os.WriteFile(name, content, os.ModePerm)
+}
+
+// verify 校验依赖和资源的内容与固定的sha256一致
+func verify(root string, deps []*parser.Dependency, pins []*parser.Pin) error {
+	for _, dep := range deps {
+		if dep.SHA256 == "" {
+			continue
+		}
+		digest, err := parser.Digest(dep.Root(root))
+		if err != nil {
+			return fmt.Errorf("依赖%s: %w", dep.Name, err)
+		}
+		if digest != dep.SHA256 {
+			return parser.Mismatch("依赖"+dep.Name, dep.SHA256, digest)
+		}
+	}
+	for _, pin := range pins {
+		content, err := os.ReadFile(filepath.FromSlash(pin.Name))
+		if err != nil {
+			return err
+		}
+		if err := pin.Verify(content); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// updateHashes 将git依赖、远程资源及已固定的依赖和资源的sha256写入声明它们的windranger.yaml
+func updateHashes(root string, deps []*parser.Dependency, pins []*parser.Pin) error {
+	sums := make(map[string]string)
+	for _, dep := range deps {
+		if dep.Git == "" && dep.SHA256 == "" {
+			continue
+		}
+		digest, err := parser.Digest(dep.Root(root))
+		if err != nil {
+			return fmt.Errorf("依赖%s: %w", dep.Name, err)
+		}
+		sums[dep.Name] = digest
+	}
+	profile := filepath.Join(root, parser.Profile)
+	resources := map[string]map[string]string{
+		profile: {},
+	}
+	for _, pin := range pins {
+		content, err := os.ReadFile(filepath.FromSlash(pin.Name))
+		if err != nil {
+			return err
+		}
+		name := filepath.FromSlash(pin.Profile)
+		if resources[name] == nil {
+			resources[name] = make(map[string]string)
+		}
+		resources[name][pin.Entry] = parser.Sum(content)
+	}
+	for name, entries := range resources {
+		if name != profile {
+			if err := rewrite(name, nil, entries); err != nil {
+				return err
+			}
+		}
+	}
+	return rewrite(profile, sums, resources[profile])
+}
+
+// rewrite 更新windranger.yaml中依赖和资源的sha256，保留其余内容和注释，没有变化时不写入
+//
+// deps按依赖名索引，resources按资源声明中的路径或URL索引
+func rewrite(profile string, deps map[string]string, resources map[string]string) error {
+	content, err := os.ReadFile(profile)
+	if err != nil {
+		return err
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return err
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return fmt.Errorf("%s格式错误", profile)
+	}
+	var changed bool
+	top := doc.Content[0]
+	for i := 0; i+1 < len(top.Content); i += 2 {
+		items := top.Content[i+1]
+		if items.Kind != yaml.SequenceNode {
+			continue
+		}
+		switch top.Content[i].Value {
+		case "dependencies":
+			for _, item := range items.Content {
+				if sum, ok := deps[lookup(item, "name")]; ok && item.Kind == yaml.MappingNode {
+					changed = setSHA256(item, sum) || changed
+				}
+			}
+		case "resources":
+			for j, item := range items.Content {
+				switch item.Kind {
+				case yaml.ScalarNode:
+					sum, ok := resources[item.Value]
+					if !ok {
+						continue
+					}
+					// 简写改为映射写法
+					key := "path"
+					if isRemote(item) {
+						key = "url"
+					}
+					items.Content[j] = &yaml.Node{
+						Kind:    yaml.MappingNode,
+						Tag:     "!!map",
+						Content: []*yaml.Node{scalar(key), item, scalar("sha256"), scalar(sum)},
+					}
+					changed = true
+				case yaml.MappingNode:
+					entry := lookup(item, "url")
+					if entry == "" {
+						entry = lookup(item, "path")
+					}
+					if sum, ok := resources[entry]; ok {
+						changed = setSHA256(item, sum) || changed
+					}
+				}
+			}
+		}
+	}
+	if !changed {
+		return nil
+	}
+	var buffer bytes.Buffer
+	encoder := yaml.NewEncoder(&buffer)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&doc); err != nil {
+		return err
+	}
+	return os.WriteFile(profile, buffer.Bytes(), os.ModePerm)
+}
+
+// lookup 映射中key对应的字符串值
+func lookup(node *yaml.Node, key string) string {
+	if node.Kind != yaml.MappingNode {
+		return ""
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1].Value
+		}
+	}
+	return ""
+}
+
+// isRemote 判断简写的资源是否为远程资源
+func isRemote(node *yaml.Node) bool {
+	var resource parser.Resource
+	return node.Decode(&resource) == nil && resource.URL != ""
+}
+
+// setSHA256 设置映射中的sha256，返回是否有变化
+func setSHA256(node *yaml.Node, sum string) bool {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == "sha256" {
+			if node.Content[i+1].Value == sum {
+				return false
+			}
+			// 原值可能被识别为数字
+			node.Content[i+1].Tag, node.Content[i+1].Style, node.Content[i+1].Value = "!!str", 0, sum
+			return true
+		}
+	}
+	node.Content = append(node.Content, scalar("sha256"), scalar(sum))
+	return true
+}
+
+// scalar 字符串节点
+func scalar(value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+}
@@ -0,0 +1,379 @@
+package diff
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/wzyjerry/windranger/internal/parser"
+)
+
+type ChangeKind uint32
+
+const (
+	ChangeAdded ChangeKind = iota
+	ChangeRemoved
+	ChangeRenamed
+	ChangeType
+	ChangeKindChanged
+	ChangeEnumValue
+	ChangeCollection
+)
+
+var changeKindName = [...]string{
+	ChangeAdded:       "added",
+	ChangeRemoved:     "removed",
+	ChangeRenamed:     "renamed",
+	ChangeType:        "type",
+	ChangeKindChanged: "kind",
+	ChangeEnumValue:   "enum",
+	ChangeCollection:  "collection",
+}
+
+// Change 模型变更
+type Change struct {
+	Kind ChangeKind
+	// Path 变更位置，如demo.name
+	Path string
+	// Message 变更说明
+	Message string
+	// Breaking 是否破坏已存储的数据或现有客户端
+	Breaking bool
+	// Old 变更前的字段，新增时为nil
+	Old *parser.Field
+	// New 变更后的字段，删除时为nil
+	New *parser.Field
+}
+
+func (c *Change) String() string {
+	level := "non-breaking"
+	if c.Breaking {
+		level = "breaking"
+	}
+	return fmt.Sprintf("[%s] %s %s: %s", level, changeKindName[c.Kind], c.Path, c.Message)
+}
+
+// HasBreaking 是否包含破坏性变更
+func HasBreaking(changes []*Change) bool {
+	for _, change := range changes {
+		if change.Breaking {
+			return true
+		}
+	}
+	return false
+}
+
+// index 按名称索引的模型
+type index struct {
+	enums       map[string]*parser.Enum
+	structures  map[string]*parser.Structure
+	collections map[string]*parser.Collection
+}
+
+func newIndex(packages []*parser.Package) *index {
+	idx := &index{
+		enums:       make(map[string]*parser.Enum),
+		structures:  make(map[string]*parser.Structure),
+		collections: make(map[string]*parser.Collection),
+	}
+	for _, pack := range packages {
+		for _, enum := range pack.Enums {
+			idx.enums[enum.Name] = enum
+		}
+		for _, structure := range pack.Structures {
+			idx.structures[structure.Name] = structure
+		}
+		if pack.Collection != nil {
+			idx.collections[pack.Name] = pack.Collection
+		}
+	}
+	return idx
+}
+
+// sortedKeys 返回字典序的键
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Compare 比较两个版本的模型，返回按位置排序的变更列表
+//
+// 结构和枚举按名称匹配，跨包移动不视为变更
+func Compare(old []*parser.Package, new []*parser.Package) []*Change {
+	o, n := newIndex(old), newIndex(new)
+	changes := make([]*Change, 0)
+	for _, name := range sortedKeys(o.collections) {
+		oc := o.collections[name]
+		nc, ok := n.collections[name]
+		if !ok {
+			changes = append(changes, &Change{
+				Kind:     ChangeCollection,
+				Path:     name,
+				Message:  fmt.Sprintf("表已删除(集合%s)", oc.Name),
+				Breaking: true,
+			})
+			continue
+		}
+		if oc.Name != nc.Name || oc.Database != nc.Database {
+			changes = append(changes, &Change{
+				Kind:     ChangeCollection,
+				Path:     name,
+				Message:  fmt.Sprintf("集合%s.%s变更为%s.%s", oc.Database, oc.Name, nc.Database, nc.Name),
+				Breaking: true,
+			})
+		}
+	}
+	for _, name := range sortedKeys(n.collections) {
+		if _, ok := o.collections[name]; !ok {
+			changes = append(changes, &Change{
+				Kind:    ChangeCollection,
+				Path:    name,
+				Message: fmt.Sprintf("新增表(集合%s)", n.collections[name].Name),
+			})
+		}
+	}
+	for _, name := range sortedKeys(o.enums) {
+		if ne, ok := n.enums[name]; ok {
+			changes = append(changes, compareEnum(o.enums[name], ne)...)
+		} else {
+			changes = append(changes, &Change{
+				Kind:     ChangeRemoved,
+				Path:     name,
+				Message:  "枚举已删除",
+				Breaking: true,
+			})
+		}
+	}
+	for _, name := range sortedKeys(n.enums) {
+		if _, ok := o.enums[name]; !ok {
+			changes = append(changes, &Change{
+				Kind:    ChangeAdded,
+				Path:    name,
+				Message: "新增枚举",
+			})
+		}
+	}
+	for _, name := range sortedKeys(o.structures) {
+		if ns, ok := n.structures[name]; ok {
+			changes = append(changes, compareStructure(o.structures[name], ns)...)
+		} else {
+			changes = append(changes, &Change{
+				Kind:     ChangeRemoved,
+				Path:     name,
+				Message:  "结构已删除",
+				Breaking: true,
+			})
+		}
+	}
+	for _, name := range sortedKeys(n.structures) {
+		if _, ok := o.structures[name]; !ok {
+			changes = append(changes, &Change{
+				Kind:    ChangeAdded,
+				Path:    name,
+				Message: "新增结构",
+			})
+		}
+	}
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+	return changes
+}
+
+// compareEnum 比较枚举值，go后端按序号存储枚举，序号变化同样是破坏性的
+func compareEnum(old *parser.Enum, new *parser.Enum) []*Change {
+	changes := make([]*Change, 0)
+	positions := make(map[string]int, len(new.EnumFields))
+	for i, field := range new.EnumFields {
+		positions[field.Name] = i
+	}
+	oldNames := make(map[string]struct{}, len(old.EnumFields))
+	for i, field := range old.EnumFields {
+		oldNames[field.Name] = struct{}{}
+		path := old.Name + "." + field.Name
+		j, ok := positions[field.Name]
+		switch {
+		case !ok:
+			changes = append(changes, &Change{
+				Kind:     ChangeEnumValue,
+				Path:     path,
+				Message:  "枚举值已删除",
+				Breaking: true,
+			})
+		case i != j:
+			changes = append(changes, &Change{
+				Kind:     ChangeEnumValue,
+				Path:     path,
+				Message:  fmt.Sprintf("枚举值序号%d变更为%d", i, j),
+				Breaking: true,
+			})
+		}
+	}
+	for i, field := range new.EnumFields {
+		if _, ok := oldNames[field.Name]; !ok {
+			changes = append(changes, &Change{
+				Kind:     ChangeEnumValue,
+				Path:     new.Name + "." + field.Name,
+				Message:  "新增枚举值",
+				Breaking: i < len(old.EnumFields),
+			})
+		}
+	}
+	return changes
+}
+
+// kindChange 判断字段属性变更是否破坏兼容
+func kindChange(old parser.Kind, new parser.Kind) (string, bool) {
+	switch {
+	case old == parser.KindOptional && new == parser.KindNormal:
+		return "可空字段变更为必填", true
+	case old == parser.KindNormal && new == parser.KindOptional:
+		return "必填字段变更为可空", false
+	}
+	return fmt.Sprintf("字段属性%s变更为%s", kindString(old), kindString(new)), true
+}
+
+func kindString(kind parser.Kind) string {
+	return strings.TrimPrefix(kind.String(), "Kind")
+}
+
+// sameShape 判断两个字段类型和属性是否一致
+func sameShape(a *parser.Field, b *parser.Field) bool {
+	return a.Type.Raw == b.Type.Raw && a.Type.Kind == b.Type.Kind
+}
+
+// matchAliases 按曾用名匹配删除与新增的字段，返回未匹配的字段
+func matchAliases(structure string, added []*parser.Field, removed []*parser.Field, changes *[]*Change) ([]*parser.Field, []*parser.Field) {
+	restAdded := make([]*parser.Field, 0, len(added))
+	matched := make(map[*parser.Field]struct{})
+	for _, nf := range added {
+		var of *parser.Field
+		for _, alias := range nf.Aliases {
+			for _, candidate := range removed {
+				if _, ok := matched[candidate]; !ok && candidate.Name == alias {
+					of = candidate
+				}
+			}
+		}
+		if of == nil {
+			restAdded = append(restAdded, nf)
+			continue
+		}
+		matched[of] = struct{}{}
+		message := fmt.Sprintf("字段重命名为%s(兼容旧名称)", nf.Name)
+		breaking := !sameShape(of, nf)
+		if breaking {
+			message += ", 类型或属性同时变更"
+		}
+		*changes = append(*changes, &Change{
+			Kind:     ChangeRenamed,
+			Path:     structure + "." + of.Name,
+			Message:  message,
+			Breaking: breaking,
+			Old:      of,
+			New:      nf,
+		})
+	}
+	restRemoved := make([]*parser.Field, 0, len(removed))
+	for _, of := range removed {
+		if _, ok := matched[of]; !ok {
+			restRemoved = append(restRemoved, of)
+		}
+	}
+	return restAdded, restRemoved
+}
+
+// compareStructure 比较结构字段
+func compareStructure(old *parser.Structure, new *parser.Structure) []*Change {
+	changes := make([]*Change, 0)
+	oldFields := make(map[string]*parser.Field, len(old.Fields))
+	for _, field := range old.Fields {
+		oldFields[field.Name] = field
+	}
+	newFields := make(map[string]*parser.Field, len(new.Fields))
+	for _, field := range new.Fields {
+		newFields[field.Name] = field
+	}
+	removed := make([]*parser.Field, 0)
+	for _, of := range old.Fields {
+		nf, ok := newFields[of.Name]
+		if !ok {
+			removed = append(removed, of)
+			continue
+		}
+		path := old.Name + "." + of.Name
+		if of.Type.Raw != nf.Type.Raw {
+			changes = append(changes, &Change{
+				Kind:     ChangeType,
+				Path:     path,
+				Message:  fmt.Sprintf("类型%s变更为%s", of.Type.Raw, nf.Type.Raw),
+				Breaking: true,
+				Old:      of,
+				New:      nf,
+			})
+		}
+		if of.Type.Kind != nf.Type.Kind {
+			message, breaking := kindChange(of.Type.Kind, nf.Type.Kind)
+			changes = append(changes, &Change{
+				Kind:     ChangeKindChanged,
+				Path:     path,
+				Message:  message,
+				Breaking: breaking,
+				Old:      of,
+				New:      nf,
+			})
+		}
+	}
+	added := make([]*parser.Field, 0)
+	for _, nf := range new.Fields {
+		if _, ok := oldFields[nf.Name]; !ok {
+			added = append(added, nf)
+		}
+	}
+	// 通过曾用名声明的重命名，生成代码解码时兼容旧名称
+	added, removed = matchAliases(old.Name, added, removed, &changes)
+	// 仅有一对类型、属性和注释一致的删除与新增字段时视为重命名
+	if len(removed) == 1 && len(added) == 1 && sameShape(removed[0], added[0]) &&
+		removed[0].Comment != "" && removed[0].Comment == added[0].Comment {
+		changes = append(changes, &Change{
+			Kind:     ChangeRenamed,
+			Path:     old.Name + "." + removed[0].Name,
+			Message:  fmt.Sprintf("字段重命名为%s", added[0].Name),
+			Breaking: true,
+			Old:      removed[0],
+			New:      added[0],
+		})
+		return changes
+	}
+	for _, of := range removed {
+		changes = append(changes, &Change{
+			Kind:     ChangeRemoved,
+			Path:     old.Name + "." + of.Name,
+			Message:  "字段已删除",
+			Breaking: true,
+			Old:      of,
+		})
+	}
+	for _, nf := range added {
+		required := nf.Type.Kind == parser.KindNormal || nf.Type.Kind == parser.KindPrimaryKey
+		message := "新增可空字段"
+		if required {
+			message = "新增必填字段"
+			if nf.Default != nil {
+				message += "(有默认值)"
+			}
+		}
+		changes = append(changes, &Change{
+			Kind:     ChangeAdded,
+			Path:     new.Name + "." + nf.Name,
+			Message:  message,
+			Breaking: required && nf.Default == nil,
+			New:      nf,
+		})
+	}
+	return changes
+}
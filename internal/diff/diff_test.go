@@ -0,0 +1,135 @@
+package diff
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/wzyjerry/windranger/internal/parser"
+)
+
+func field(name string, raw string, kind parser.Kind, comment string) *parser.Field {
+	return &parser.Field{
+		Name:    name,
+		Comment: comment,
+		Type:    &parser.Type{Raw: raw, Kind: kind},
+	}
+}
+
+func messages(changes []*Change) []string {
+	result := make([]string, len(changes))
+	for i, change := range changes {
+		result[i] = change.String()
+	}
+	return result
+}
+
+func TestCompare(t *testing.T) {
+	validator := require.New(t)
+	old := []*parser.Package{{
+		Name: "demo",
+		Enums: []*parser.Enum{{
+			Name:       "gender",
+			EnumFields: []*parser.EnumField{{Name: "unset"}, {Name: "male"}, {Name: "female"}},
+		}},
+		Structures: []*parser.Structure{{
+			Name: "demo",
+			Fields: []*parser.Field{
+				field("id", "string", parser.KindPrimaryKey, ""),
+				field("name", "string", parser.KindOptional, ""),
+				field("age", "int", parser.KindNormal, ""),
+				field("tags", "string", parser.KindArray, ""),
+				field("old", "string", parser.KindNormal, ""),
+			},
+		}},
+		Collection: &parser.Collection{Name: "demo"},
+	}}
+	def := "0"
+	score := field("score", "float", parser.KindNormal, "")
+	score.Default = &def
+	new := []*parser.Package{{
+		Name: "demo",
+		Enums: []*parser.Enum{{
+			Name:       "gender",
+			EnumFields: []*parser.EnumField{{Name: "unset"}, {Name: "female"}, {Name: "other"}},
+		}},
+		Structures: []*parser.Structure{{
+			Name: "demo",
+			Fields: []*parser.Field{
+				field("id", "string", parser.KindPrimaryKey, ""),
+				field("name", "string", parser.KindNormal, ""),
+				field("age", "float", parser.KindOptional, ""),
+				field("tags", "string", parser.KindArray, ""),
+				field("nick", "string", parser.KindOptional, ""),
+				score,
+			},
+		}},
+		Collection: &parser.Collection{Name: "demos"},
+	}}
+	changes := Compare(old, new)
+	validator.Equal([]string{
+		"[breaking] collection demo: 集合.demo变更为.demos",
+		"[breaking] type demo.age: 类型int变更为float",
+		"[non-breaking] kind demo.age: 必填字段变更为可空",
+		"[breaking] kind demo.name: 可空字段变更为必填",
+		"[non-breaking] added demo.nick: 新增可空字段",
+		"[breaking] removed demo.old: 字段已删除",
+		"[non-breaking] added demo.score: 新增必填字段(有默认值)",
+		"[breaking] enum gender.female: 枚举值序号2变更为1",
+		"[breaking] enum gender.male: 枚举值已删除",
+		"[breaking] enum gender.other: 新增枚举值",
+	}, messages(changes))
+	validator.True(HasBreaking(changes))
+	validator.False(HasBreaking(Compare(old, old)))
+}
+
+func TestCompareRename(t *testing.T) {
+	validator := require.New(t)
+	old := []*parser.Package{{
+		Name: "demo",
+		Structures: []*parser.Structure{{
+			Name:   "demo",
+			Fields: []*parser.Field{field("name", "string", parser.KindNormal, "名称")},
+		}},
+	}}
+	new := []*parser.Package{{
+		Name: "demo",
+		Structures: []*parser.Structure{{
+			Name:   "demo",
+			Fields: []*parser.Field{field("full_name", "string", parser.KindNormal, "名称")},
+		}},
+	}}
+	validator.Equal([]string{
+		"[breaking] renamed demo.name: 字段重命名为full_name",
+	}, messages(Compare(old, new)))
+}
+
+func TestCompareAlias(t *testing.T) {
+	validator := require.New(t)
+	old := []*parser.Package{{
+		Name: "demo",
+		Structures: []*parser.Structure{{
+			Name: "demo",
+			Fields: []*parser.Field{
+				field("title", "string", parser.KindNormal, ""),
+				field("name", "string", parser.KindNormal, ""),
+			},
+		}},
+	}}
+	heading := field("heading", "string", parser.KindNormal, "")
+	heading.Aliases = []string{"caption", "title"}
+	new := []*parser.Package{{
+		Name: "demo",
+		Structures: []*parser.Structure{{
+			Name: "demo",
+			Fields: []*parser.Field{
+				heading,
+				field("nick", "string", parser.KindOptional, ""),
+			},
+		}},
+	}}
+	validator.Equal([]string{
+		"[breaking] removed demo.name: 字段已删除",
+		"[non-breaking] added demo.nick: 新增可空字段",
+		"[non-breaking] renamed demo.title: 字段重命名为heading(兼容旧名称)",
+	}, messages(Compare(old, new)))
+}
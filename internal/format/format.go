@@ -0,0 +1,281 @@
+package format
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/wzyjerry/windranger/internal/parser"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// EnumKeep 保持枚举原有风格
+	EnumKeep = "keep"
+	// EnumBlock 枚举使用块风格
+	EnumBlock = "block"
+	// EnumFlow 枚举使用流风格，例如[a, b]
+	EnumFlow = "flow"
+)
+
+// Options 格式化选项
+type Options struct {
+	// Indent 缩进空格数
+	Indent int
+	// EnumStyle 枚举风格: keep、block或flow
+	EnumStyle string
+	// SortFields 按主键优先、字典序排列字段
+	SortFields bool
+}
+
+// DefaultOptions 默认格式化选项
+func DefaultOptions() *Options {
+	return &Options{
+		Indent:    2,
+		EnumStyle: EnumKeep,
+	}
+}
+
+// Format 将模型yaml格式化为规范形式，保留注释
+func Format(content []byte, opts *Options) ([]byte, error) {
+	switch opts.EnumStyle {
+	case EnumKeep, EnumBlock, EnumFlow:
+	default:
+		return nil, fmt.Errorf("未知枚举风格: %s", opts.EnumStyle)
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	buffer := bytes.NewBuffer(nil)
+	encoder := yaml.NewEncoder(buffer)
+	encoder.SetIndent(opts.Indent)
+	for {
+		var doc yaml.Node
+		err := decoder.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if spec := lookup(&doc, "spec"); spec != nil {
+			var version string
+			if node := lookup(&doc, "version"); node != nil {
+				version = node.Value
+			}
+			normalize(spec, version == parser.VersionV2, opts)
+		}
+		if err := encoder.Encode(&doc); err != nil {
+			return nil, err
+		}
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return alignComments(buffer.Bytes()), nil
+}
+
+// lookup 查找文档根字典中的键
+func lookup(doc *yaml.Node, key string) *yaml.Node {
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 {
+		return nil
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i < len(root.Content)>>1; i++ {
+		if root.Content[i<<1].Value == key {
+			return root.Content[i<<1|1]
+		}
+	}
+	return nil
+}
+
+// isPrimaryKey 是否为主键字段
+func isPrimaryKey(key *yaml.Node) bool {
+	return strings.HasSuffix(key.Value, "!")
+}
+
+// normalize 递归处理spec中的枚举风格和字段顺序，长格式字段的属性保持原样
+func normalize(node *yaml.Node, v2 bool, opts *Options) {
+	switch node.Kind {
+	case yaml.SequenceNode:
+		switch opts.EnumStyle {
+		case EnumBlock:
+			node.Style &^= yaml.FlowStyle
+		case EnumFlow:
+			// 带注释的枚举值无法使用流风格
+			for _, item := range node.Content {
+				if item.Kind != yaml.ScalarNode || item.HeadComment != "" || item.LineComment != "" || item.FootComment != "" {
+					return
+				}
+			}
+			node.Style |= yaml.FlowStyle
+		}
+	case yaml.MappingNode:
+		if v2 && parser.IsLongForm(node) {
+			return
+		}
+		for i := 0; i < len(node.Content)>>1; i++ {
+			normalize(node.Content[i<<1|1], v2, opts)
+		}
+		if opts.SortFields {
+			sortFields(node)
+		}
+	}
+}
+
+// sortFields 按主键优先、字典序排列字典中的键值对
+func sortFields(node *yaml.Node) {
+	pairs := make([][2]*yaml.Node, len(node.Content)>>1)
+	for i := range pairs {
+		pairs[i] = [2]*yaml.Node{node.Content[i<<1], node.Content[i<<1|1]}
+	}
+	sort.SliceStable(pairs, func(i, j int) bool {
+		pi, pj := isPrimaryKey(pairs[i][0]), isPrimaryKey(pairs[j][0])
+		if pi != pj {
+			return pi
+		}
+		return pairs[i][0].Value < pairs[j][0].Value
+	})
+	for i, pair := range pairs {
+		node.Content[i<<1], node.Content[i<<1|1] = pair[0], pair[1]
+	}
+}
+
+// commentIndex 返回行内注释的起始位置，忽略引号中的'#'
+func commentIndex(line string) int {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && i > 0 && line[i-1] == ' ':
+			if strings.TrimSpace(line[:i]) == "" {
+				return -1
+			}
+			return i
+		}
+	}
+	return -1
+}
+
+// indentOf 返回行首空格数
+func indentOf(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
+
+// width 返回字符串的显示宽度，东亚宽字符占两列
+func width(s string) int {
+	result := 0
+	for _, r := range s {
+		result++
+		if isWide(r) {
+			result++
+		}
+	}
+	return result
+}
+
+// isWide 判断字符是否为东亚宽字符
+func isWide(r rune) bool {
+	switch {
+	case r < 0x1100:
+		return false
+	case r <= 0x115f, // 谚文字母
+		0x2e80 <= r && r <= 0x303e, // 中日韩部首、标点
+		0x3041 <= r && r <= 0x33ff, // 假名、注音及中日韩兼容字符
+		0x3400 <= r && r <= 0x4dbf, // 中日韩统一表意文字扩展A
+		0x4e00 <= r && r <= 0x9fff, // 中日韩统一表意文字
+		0xa000 <= r && r <= 0xa4cf, // 彝文
+		0xac00 <= r && r <= 0xd7a3, // 谚文音节
+		0xf900 <= r && r <= 0xfaff, // 中日韩兼容表意文字
+		0xfe30 <= r && r <= 0xfe4f, // 中日韩兼容形式
+		0xff00 <= r && r <= 0xff60, // 全角字符
+		0xffe0 <= r && r <= 0xffe6,
+		0x1f300 <= r && r <= 0x1f64f, // 表情符号
+		0x1f900 <= r && r <= 0x1f9ff,
+		0x20000 <= r && r <= 0x3fffd: // 中日韩统一表意文字扩展B及以后
+		return true
+	}
+	return false
+}
+
+// commentLines 返回带行内注释的行号（从1开始），块标量内容中的'#'不是注释，不会包含在内
+func commentLines(content []byte) map[int]struct{} {
+	lines := make(map[int]struct{})
+	var walk func(node *yaml.Node)
+	walk = func(node *yaml.Node) {
+		switch node.Kind {
+		case yaml.MappingNode:
+			for i := 0; i < len(node.Content)>>1; i++ {
+				key, value := node.Content[i<<1], node.Content[i<<1|1]
+				// 块风格集合的注释位于键所在的行
+				if key.LineComment != "" || value.LineComment != "" {
+					lines[key.Line] = struct{}{}
+				}
+				walk(value)
+			}
+			return
+		case yaml.SequenceNode:
+			for _, item := range node.Content {
+				if item.LineComment != "" {
+					lines[item.Line] = struct{}{}
+				}
+			}
+		}
+		for _, child := range node.Content {
+			walk(child)
+		}
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	for {
+		var doc yaml.Node
+		if err := decoder.Decode(&doc); err != nil {
+			break
+		}
+		walk(&doc)
+	}
+	return lines
+}
+
+// alignComments 按显示宽度对齐相邻同缩进行的行内注释，只处理yaml节点上的行内注释
+func alignComments(content []byte) []byte {
+	comments := commentLines(content)
+	lines := strings.Split(string(content), "\n")
+	// hasComment 第i行（从0开始）是否带行内注释
+	hasComment := func(i int) bool {
+		_, ok := comments[i+1]
+		return ok && commentIndex(lines[i]) >= 0
+	}
+	for start := 0; start < len(lines); {
+		if !hasComment(start) {
+			start++
+			continue
+		}
+		end := start + 1
+		for end < len(lines) && hasComment(end) && indentOf(lines[end]) == indentOf(lines[start]) {
+			end++
+		}
+		column := 0
+		for _, line := range lines[start:end] {
+			if w := width(strings.TrimRight(line[:commentIndex(line)], " ")); w > column {
+				column = w
+			}
+		}
+		for i := start; i < end; i++ {
+			index := commentIndex(lines[i])
+			code := strings.TrimRight(lines[i][:index], " ")
+			lines[i] = code + strings.Repeat(" ", column-width(code)+1) + lines[i][index:]
+		}
+		start = end
+	}
+	return []byte(strings.Join(lines, "\n"))
+}
@@ -0,0 +1,125 @@
+package format
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const source = `version: v1
+kind:    Model
+metadata:
+    name: demo
+spec:
+    demo:
+        # 名称
+        name: string # 名称
+        id!: objectid # 主键
+        status: [active, inactive]
+        type: # 类型
+            - a # 甲
+            - b
+`
+
+func TestFormat(t *testing.T) {
+	validator := require.New(t)
+	result, err := Format([]byte(source), DefaultOptions())
+	validator.Nil(err)
+	validator.Equal(`version: v1
+kind: Model
+metadata:
+  name: demo
+spec:
+  demo:
+    # 名称
+    name: string  # 名称
+    id!: objectid # 主键
+    status: [active, inactive]
+    type: # 类型
+      - a # 甲
+      - b
+`, string(result))
+	again, err := Format(result, DefaultOptions())
+	validator.Nil(err)
+	validator.Equal(string(result), string(again))
+}
+
+func TestFormatEnumStyle(t *testing.T) {
+	validator := require.New(t)
+	opts := DefaultOptions()
+	opts.EnumStyle = EnumBlock
+	result, err := Format([]byte(source), opts)
+	validator.Nil(err)
+	validator.Contains(string(result), "status:\n      - active\n      - inactive\n")
+	opts.EnumStyle = EnumFlow
+	result, err = Format([]byte(source), opts)
+	validator.Nil(err)
+	validator.Contains(string(result), "status: [active, inactive]\n")
+	// 带注释的枚举保持块风格
+	validator.Contains(string(result), "      - a # 甲\n")
+	opts.EnumStyle = "unknown"
+	_, err = Format([]byte(source), opts)
+	validator.NotNil(err)
+}
+
+func TestFormatSort(t *testing.T) {
+	validator := require.New(t)
+	opts := DefaultOptions()
+	opts.SortFields = true
+	result, err := Format([]byte(`version: v2
+kind: Model
+metadata:
+  name: demo
+spec:
+  demo:
+    title:
+      type: string
+      description: 标题
+    author: string
+    id!: objectid
+`), opts)
+	validator.Nil(err)
+	validator.Equal(`version: v2
+kind: Model
+metadata:
+  name: demo
+spec:
+  demo:
+    id!: objectid
+    author: string
+    title:
+      type: string
+      description: 标题
+`, string(result))
+}
+
+func TestAlignComments(t *testing.T) {
+	validator := require.New(t)
+	// 按显示宽度对齐，中文占两列
+	validator.Equal(`a: 1       # 一
+long: 名称 # 二
+nested:
+  b: "#" # 三
+`, string(alignComments([]byte(`a: 1 # 一
+long: 名称 # 二
+nested:
+  b: "#" # 三
+`))))
+	// 块标量中的'#'是内容，不是注释
+	block := `a: 1 # 一
+description: |
+  x  # spaced
+  longer line # 内容
+---
+b: 2 # 二
+c: 名称 # 三
+`
+	validator.Equal(`a: 1 # 一
+description: |
+  x  # spaced
+  longer line # 内容
+---
+b: 2    # 二
+c: 名称 # 三
+`, string(alignComments([]byte(block))))
+}
@@ -0,0 +1,189 @@
+package custom
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/wzyjerry/windranger/internal/generator/gogo"
+	"github.com/wzyjerry/windranger/internal/output"
+	"github.com/wzyjerry/windranger/internal/parser"
+	"github.com/wzyjerry/windranger/internal/region"
+	"github.com/wzyjerry/windranger/internal/util"
+	"gopkg.in/yaml.v3"
+)
+
+// Manifest 模板目录中的清单文件名
+const Manifest = "template.yaml"
+
+const (
+	// ScopePackage 每个包渲染一次
+	ScopePackage = "package"
+	// ScopeStructure 每个结构渲染一次
+	ScopeStructure = "structure"
+	// ScopeEnum 每个枚举渲染一次
+	ScopeEnum = "enum"
+)
+
+type (
+	// Config 模板清单
+	Config struct {
+		Templates []*Template `yaml:"templates"`
+	}
+	// Template 单个模板的渲染配置
+	Template struct {
+		// Name 模板文件名
+		Name string `yaml:"name"`
+		// Scope 渲染粒度，package、structure或enum，默认package
+		Scope string `yaml:"scope"`
+		// Output 输出路径模板，渲染结果为空时跳过
+		Output string `yaml:"output"`
+	}
+	// InfoCustom 用户模板信息
+	InfoCustom struct {
+		// Packages 全部包
+		Packages []*parser.Package
+		// Package 当前包
+		Package *parser.Package
+		// Structure 当前结构，scope为structure时有效
+		Structure *parser.Structure
+		// Enum 当前枚举，scope为enum时有效
+		Enum *parser.Enum
+		// Parameters 命令行传入的模板参数
+		Parameters map[string]string
+	}
+)
+
+// load 读取清单并解析全部模板，模板之间可以互相引用
+func load(fsys fs.FS) (*Config, *template.Template, error) {
+	content, err := fs.ReadFile(fsys, Manifest)
+	if err != nil {
+		return nil, nil, fmt.Errorf("读取模板清单失败: %w", err)
+	}
+	config := new(Config)
+	if err := yaml.Unmarshal(content, config); err != nil {
+		return nil, nil, fmt.Errorf("模板清单格式错误: %w", err)
+	}
+	t, err := template.New(Manifest).Funcs(util.FuncMap).ParseFS(fsys, "*.tmpl")
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, item := range config.Templates {
+		if t.Lookup(item.Name) == nil {
+			return nil, nil, fmt.Errorf("找不到模板: %s", item.Name)
+		}
+		if item.Output == "" {
+			return nil, nil, fmt.Errorf("模板%s缺少输出路径", item.Name)
+		}
+		switch item.Scope {
+		case "":
+			item.Scope = ScopePackage
+		case ScopePackage, ScopeStructure, ScopeEnum:
+		default:
+			return nil, nil, fmt.Errorf("模板%s的渲染粒度无效: %s", item.Name, item.Scope)
+		}
+	}
+	return config, t, nil
+}
+
+// execute 渲染单个模板，返回相对于输出目录的路径和内容，路径为空时跳过
+func execute(t *template.Template, item *Template, info *InfoCustom) (string, []byte, error) {
+	output, err := template.New(item.Name + ":output").Funcs(util.FuncMap).Parse(item.Output)
+	if err != nil {
+		return "", nil, err
+	}
+	buffer := bytes.NewBuffer(nil)
+	if err := output.Execute(buffer, info); err != nil {
+		return "", nil, err
+	}
+	name := strings.TrimSpace(buffer.String())
+	if name == "" {
+		return "", nil, nil
+	}
+	clean := path.Clean(name)
+	if path.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, "../") {
+		return "", nil, fmt.Errorf("模板%s的输出路径无效: %s", item.Name, name)
+	}
+	buffer = bytes.NewBuffer(nil)
+	if err := t.ExecuteTemplate(buffer, item.Name, info); err != nil {
+		return "", nil, err
+	}
+	return clean, buffer.Bytes(), nil
+}
+
+// Render 按模板目录渲染全部文件，保留已有文件中的自定义区域，多个模板输出到同一文件时报错
+func Render(fsys fs.FS, packages []*parser.Package, parameters map[string]string, out string) (*output.Set, error) {
+	config, t, err := load(fsys)
+	if err != nil {
+		return nil, err
+	}
+	packages, errs := gogo.Link(packages)
+	if len(errs) != 0 {
+		return nil, errs[0]
+	}
+	set := output.NewSet()
+	seen := make(map[string]string)
+	add := func(item *Template, info *InfoCustom) error {
+		name, content, err := execute(t, item, info)
+		if err != nil || name == "" {
+			return err
+		}
+		if previous, ok := seen[name]; ok {
+			return fmt.Errorf("模板%s与%s输出到同一文件: %s", item.Name, previous, name)
+		}
+		seen[name] = item.Name
+		filename := filepath.Join(out, filepath.FromSlash(name))
+		content, err = region.Preserve(filename, content)
+		if err != nil {
+			return err
+		}
+		set.Add(filename, content)
+		return nil
+	}
+	for _, item := range config.Templates {
+		// 依赖项目的包只出现在.Packages中
+		for _, pack := range parser.Own(packages) {
+			info := &InfoCustom{
+				Packages:   packages,
+				Package:    pack,
+				Parameters: parameters,
+			}
+			switch item.Scope {
+			case ScopePackage:
+				err = add(item, info)
+			case ScopeStructure:
+				for _, structure := range pack.Structures {
+					info.Structure = structure
+					if err = add(item, info); err != nil {
+						break
+					}
+				}
+			case ScopeEnum:
+				for _, enum := range pack.Enums {
+					info.Enum = enum
+					if err = add(item, info); err != nil {
+						break
+					}
+				}
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return set, nil
+}
+
+// Generate 渲染模板目录并写入输出目录
+func Generate(dir string, packages []*parser.Package, parameters map[string]string, out string) error {
+	set, err := Render(os.DirFS(dir), packages, parameters, out)
+	if err != nil {
+		return err
+	}
+	return set.Write()
+}
@@ -0,0 +1,104 @@
+package custom
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+	"github.com/wzyjerry/windranger/internal/output"
+	"github.com/wzyjerry/windranger/internal/parser"
+)
+
+const model = `version: v1
+kind: Model
+metadata:
+  name: user_info
+spec:
+  # 用户
+  user_info:
+    id!: objectid # 主键
+    tags[]: string # 标签
+    home?: address # 住址
+    status: # 状态
+      - active # 启用
+      - inactive # 停用
+  # 地址
+  address:
+    city: string # 城市
+`
+
+func parse(t *testing.T) []*parser.Package {
+	packages, errs := parser.NewParser().AddYaml([]byte(model)).Parse()
+	require.Nil(t, errs)
+	return packages
+}
+
+func TestRender(t *testing.T) {
+	validator := require.New(t)
+	fsys := fstest.MapFS{
+		Manifest: {Data: []byte(`templates:
+  - name: index.ts.tmpl
+    output: "{{ .Package.Name | kebab }}/index.ts"
+  - name: model.ts.tmpl
+    scope: structure
+    output: "{{ .Package.Name | kebab }}/{{ .Structure.Name | kebab }}.ts"
+  - name: enum.py.tmpl
+    scope: enum
+    output: "{{ if eq .Parameters.python \"on\" }}{{ .Enum.Name | snake }}.py{{ end }}"
+`)},
+		"index.ts.tmpl": {Data: []byte(`{{- range .Package.Structures }}export * from './{{ .Name | kebab }}';
+{{ end }}`)},
+		"model.ts.tmpl": {Data: []byte(`{{ template "comment" .Structure.Comment }}export interface {{ .Structure.Ident }} {
+{{- range .Structure.Fields }}
+  {{ camel .Name }}: {{ tsType .Type }};
+{{- end }}
+}
+`)},
+		"common.tmpl":   {Data: []byte(`{{ define "comment" }}// {{ . }}` + "\n" + `{{ end }}`)},
+		"enum.py.tmpl":  {Data: []byte(`class {{ .Enum.Ident }}(str, Enum):{{ range .Enum.EnumFields }} {{ quote .Name }}{{ end }}`)},
+		"unused.md.txt": {Data: []byte(`ignored`)},
+	}
+	set, err := Render(fsys, parse(t), map[string]string{"python": "on"}, "")
+	validator.Nil(err)
+	validator.Equal([]*output.File{
+		{Path: "user-info/index.ts", Content: []byte("export * from './address';\nexport * from './user-info';\n")},
+		{Path: "user-info/address.ts", Content: []byte(`// 地址
+export interface Address {
+  city: string;
+}
+`)},
+		{Path: "user-info/user-info.ts", Content: []byte(`// 用户
+export interface UserInfo {
+  id: string;
+  tags: string[];
+  home: Address | null;
+  status: Status;
+}
+`)},
+		{Path: "status.py", Content: []byte(`class Status(str, Enum): "active" "inactive"`)},
+	}, set.Files())
+
+	// 输出路径为空时跳过
+	set, err = Render(fsys, parse(t), nil, "")
+	validator.Nil(err)
+	validator.Len(set.Files(), 3)
+}
+
+func TestRenderFault(t *testing.T) {
+	validator := require.New(t)
+	for manifest, message := range map[string]string{
+		`templates: [{name: missing.tmpl, output: a}]`:             "找不到模板: missing.tmpl",
+		`templates: [{name: a.tmpl}]`:                              "模板a.tmpl缺少输出路径",
+		`templates: [{name: a.tmpl, output: a, scope: field}]`:     "模板a.tmpl的渲染粒度无效: field",
+		`templates: [{name: a.tmpl, output: ../a}]`:                "模板a.tmpl的输出路径无效: ../a",
+		`templates: [{name: a.tmpl, output: a, scope: structure}]`: "模板a.tmpl与a.tmpl输出到同一文件: a",
+	} {
+		_, err := Render(fstest.MapFS{
+			Manifest: {Data: []byte(manifest)},
+			"a.tmpl": {Data: []byte(`a`)},
+		}, parse(t), nil, "")
+		validator.EqualError(err, message)
+	}
+	_, err := Render(fstest.MapFS{"a.tmpl": {Data: []byte(`a`)}}, parse(t), nil, "")
+	validator.ErrorContains(err, "读取模板清单失败")
+}
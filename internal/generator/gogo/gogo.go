@@ -2,13 +2,19 @@ package gogo
 
 import (
 	"bytes"
-	"os"
+	"fmt"
 	"path"
+	"runtime"
 	"sort"
+	"sync"
 	"text/template"
 
+	"github.com/wzyjerry/windranger/internal/cache"
+	"github.com/wzyjerry/windranger/internal/ir"
 	"github.com/wzyjerry/windranger/internal/linker"
+	"github.com/wzyjerry/windranger/internal/output"
 	"github.com/wzyjerry/windranger/internal/parser"
+	"github.com/wzyjerry/windranger/internal/region"
 	tmpl "github.com/wzyjerry/windranger/internal/template"
 	"github.com/wzyjerry/windranger/internal/util"
 )
@@ -19,71 +25,250 @@ type InfoGogo struct {
 	PackageName string
 	// Imports 导入的go文件
 	Imports []string
+	// Aliases 导入路径的别名，依赖项目的包以依赖名导入
+	Aliases map[string]string
 
 	// Enums 枚举类型
 	Enums []*parser.Enum
 	// Structures 结构
 	Structures []*parser.Structure
+	// Table 表结构，非表所在的包为nil
+	Table *parser.Structure
+	// Collection 集合元数据
+	Collection *parser.Collection
 }
 
-func Generate(packages []*parser.Package, out string) error {
-	l := linker.NewLinker().AddPackages(packages).SetFieldFunc(util.ProtoPascal)
-	l.
-		AddTypemap("int", "int64", "").
-		AddTypemap("float", "float64", "").
-		AddTypemap("bool", "bool", "").
-		AddTypemap("string", "string", "").
-		AddTypemap("datetime", "Time", "time").
-		AddTypemap("objectid", "ObjectID", "primitive")
-	packages, errs := l.Link()
-	if len(errs) != 0 {
-		return errs[0]
+// Typemap 内置类型对应的Go类型
+var Typemap = map[string]*parser.Type{
+	"int":      {Name: "int64"},
+	"float":    {Name: "float64"},
+	"bool":     {Name: "bool"},
+	"string":   {Name: "string"},
+	"datetime": {Name: "Time", Package: "time"},
+	"objectid": {Name: "ObjectID", Package: "primitive"},
+}
+
+// Link 按Go类型映射和标识符策略链接包
+func Link(packages []*parser.Package) ([]*parser.Package, []error) {
+	l := linker.NewLinker().AddPackages(packages).SetFieldFunc(util.ProtoPascal).SetIdentPolicy(Ident)
+	for src, t := range Typemap {
+		l.AddTypemap(src, t.Name, t.Package)
 	}
-	for _, pack := range packages {
-		imports := make([]string, len(pack.Dependencies))
-		for i, dep := range pack.Dependencies {
-			switch dep {
-			case "time":
-				imports[i] = "time"
-			case "primitive":
-				imports[i] = "go.mongodb.org/mongo-driver/bson/primitive"
-			default:
-				panic("跨文件引用")
-			}
+	return l.Link()
+}
+
+// renderer 渲染上下文，模板只解析一次，可以并发渲染多个包
+type renderer struct {
+	packages []*parser.Package
+	out      string
+	cache    *cache.Cache
+	template *template.Template
+	// source 模板源码，作为缓存键的一部分
+	source string
+	// imports 依赖名到依赖项目生成的go包的导入路径
+	imports map[string]string
+}
+
+// render 渲染单个包，返回文件路径和保留自定义区域后的内容
+func (r *renderer) render(pack *parser.Package) (string, []byte, error) {
+	imports := make([]string, len(pack.Dependencies))
+	aliases := make(map[string]string)
+	for i, dep := range pack.Dependencies {
+		switch dep {
+		case "time":
+			imports[i] = "time"
+		case "primitive":
+			imports[i] = "go.mongodb.org/mongo-driver/bson/primitive"
+		default:
+			// 依赖项目的包，external已确认配置了导入路径
+			imports[i] = r.imports[dep]
+			aliases[imports[i]] = dep
 		}
-		sort.SliceStable(imports, func(i, j int) bool {
-			return imports[i] < imports[j]
-		})
-		// 准备生成信息
-		_, folder := path.Split(out)
-		info := &InfoGogo{
-			PackageName: util.Camel(folder),
-			Imports:     imports,
-			Enums:       pack.Enums,
-			Structures:  pack.Structures,
+	}
+	var table *parser.Structure
+	if pack.Collection != nil {
+		for _, structure := range pack.Structures {
+			if structure.Name == pack.Name {
+				table = structure
+			}
 		}
-		// 准备生成目录
-		err := os.MkdirAll(out, os.ModePerm)
-		if err != nil {
-			return err
+		if len(pack.Collection.Indexes) != 0 || pack.Collection.ShardKey != nil {
+			imports = append(imports,
+				"context",
+				"go.mongodb.org/mongo-driver/bson",
+				"go.mongodb.org/mongo-driver/mongo",
+				"go.mongodb.org/mongo-driver/mongo/options",
+			)
 		}
-		// 准备模板
-		name := "gogo.tmpl"
-		t, err := template.New("gogo").Funcs(util.FuncMap).ParseFS(tmpl.FS, path.Join("gogo", name))
-		if err != nil {
-			return err
+	}
+	for _, structure := range pack.Structures {
+		if util.HasAliases(structure) {
+			imports = append(imports,
+				"encoding/json",
+				"go.mongodb.org/mongo-driver/bson",
+			)
+			break
 		}
-		// 生成
+	}
+	imports = util.Unique(imports)
+	sort.SliceStable(imports, func(i, j int) bool {
+		return imports[i] < imports[j]
+	})
+	// 准备生成信息
+	_, folder := path.Split(r.out)
+	packageName, err := Ident.Sanitize(util.Camel(folder))
+	if err != nil {
+		return "", nil, err
+	}
+	info := &InfoGogo{
+		PackageName: packageName,
+		Imports:     imports,
+		Aliases:     aliases,
+		Enums:       pack.Enums,
+		Structures:  pack.Structures,
+		Table:       table,
+		Collection:  pack.Collection,
+	}
+	// 命中缓存时跳过渲染，相互引用的包闭包相同，键中需要包含包名
+	key := cache.Key("gogo", r.source, packageName, pack.Name, ir.Hash(cache.Closure(r.packages, pack)), fmt.Sprint(aliases))
+	rendered, ok := r.cache.Get(cache.KindRender, key)
+	if !ok {
 		buffer := bytes.NewBuffer(nil)
-		err = t.ExecuteTemplate(buffer, name, info)
-		if err != nil {
-			return err
+		if err := r.template.Execute(buffer, info); err != nil {
+			return "", nil, err
+		}
+		rendered = buffer.Bytes()
+		if err := r.cache.Put(cache.KindRender, key, rendered); err != nil {
+			return "", nil, err
+		}
+	}
+	// 保留自定义区域
+	filename := path.Join(r.out, util.Camel(pack.Name)+".go")
+	content, err := region.Preserve(filename, rendered)
+	if err != nil {
+		return "", nil, err
+	}
+	return filename, content, nil
+}
+
+// external 将本项目引用的依赖项目类型链接到imports中配置的go包，依赖名作为包的别名
+//
+// 本项目定义了同名类型时使用本项目的类型
+func external(packages []*parser.Package, imports map[string]string) error {
+	local := make(map[string]struct{})
+	for _, pack := range parser.Own(packages) {
+		for _, enum := range pack.Enums {
+			local[enum.Name] = struct{}{}
 		}
-		// 写文件
-		err = os.WriteFile(path.Join(out, util.Camel(pack.Name)+".go"), buffer.Bytes(), os.ModePerm)
-		if err != nil {
-			return err
+		for _, structure := range pack.Structures {
+			local[structure.Name] = struct{}{}
 		}
 	}
+	owners := make(map[string]string)
+	for _, pack := range packages {
+		if pack.Module == "" {
+			continue
+		}
+		for _, enum := range pack.Enums {
+			owners[enum.Name] = pack.Module
+		}
+		for _, structure := range pack.Structures {
+			owners[structure.Name] = pack.Module
+		}
+	}
+	for _, pack := range parser.Own(packages) {
+		for _, structure := range pack.Structures {
+			for _, field := range structure.Fields {
+				if _, ok := local[field.Type.Raw]; ok {
+					continue
+				}
+				module, ok := owners[field.Type.Raw]
+				if !ok {
+					continue
+				}
+				if imports[module] == "" {
+					return &parser.Error{
+						Pos: field.Type.Pos,
+						Err: fmt.Errorf("引用了依赖%s的类型%s，请使用--dep-import %s=<导入路径>指定依赖生成的go包，或使用--with-deps一并生成", module, field.Type.Raw, module),
+					}
+				}
+				field.Type.Package = module
+				pack.Dependencies = append(pack.Dependencies, module)
+			}
+		}
+		pack.Dependencies = util.Unique(pack.Dependencies)
+		sort.Strings(pack.Dependencies)
+	}
 	return nil
 }
+
+// Render 渲染本项目每个包的go文件，保留已有文件中的自定义区域
+//
+// 各包并发渲染，结果按包名排序，与顺序渲染相同。
+// c不为nil时按包及其引用的包的哈希缓存渲染结果，只重新渲染受影响的包。
+// 依赖项目的包不生成，本项目引用的依赖项目类型从imports中按依赖名配置的go包导入
+func Render(packages []*parser.Package, out string, c *cache.Cache, imports map[string]string) (*output.Set, error) {
+	packages, errs := Link(packages)
+	if len(errs) != 0 {
+		return nil, errs[0]
+	}
+	if err := external(packages, imports); err != nil {
+		return nil, err
+	}
+	// 准备模板
+	name := "gogo.tmpl"
+	source, err := tmpl.FS.ReadFile(path.Join("gogo", name))
+	if err != nil {
+		return nil, err
+	}
+	t, err := template.New(name).Funcs(util.FuncMap).Parse(string(source))
+	if err != nil {
+		return nil, err
+	}
+	r := &renderer{
+		packages: packages,
+		out:      out,
+		cache:    c,
+		template: t,
+		source:   string(source),
+		imports:  imports,
+	}
+	// 依赖项目的包参与链接，不生成
+	packages = parser.Own(packages)
+	filenames := make([]string, len(packages))
+	contents := make([][]byte, len(packages))
+	errors := make([]error, len(packages))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < runtime.GOMAXPROCS(0) && w < len(packages); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				filenames[i], contents[i], errors[i] = r.render(packages[i])
+			}
+		}()
+	}
+	for i := range packages {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	set := output.NewSet()
+	for i := range packages {
+		if errors[i] != nil {
+			return nil, errors[i]
+		}
+		set.Add(filenames[i], contents[i])
+	}
+	return set, nil
+}
+
+// Generate 生成每个包的go文件
+func Generate(packages []*parser.Package, out string) error {
+	set, err := Render(packages, out, nil, nil)
+	if err != nil {
+		return err
+	}
+	return set.Write()
+}
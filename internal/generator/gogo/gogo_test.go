@@ -0,0 +1,293 @@
+package gogo
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+	"github.com/wzyjerry/windranger/internal/cache"
+	"github.com/wzyjerry/windranger/internal/parser"
+)
+
+// schema 生成包含n个表的模型
+func schema(t testing.TB, n int) []*parser.Package {
+	p := parser.NewParser()
+	for i := 0; i < n; i++ {
+		var builder strings.Builder
+		fmt.Fprintf(&builder, `version: v1
+kind: Model
+metadata:
+  name: table%d
+  indexes:
+    - keys: [field0]
+spec:
+  # 表%d
+  table%d:
+    id!: objectid # 主键
+    created_at: datetime # 创建时间
+`, i, i, i)
+		for j := 0; j < 30; j++ {
+			fmt.Fprintf(&builder, "    field%d: string # 字段%d\n", j, j)
+		}
+		p.AddYamlFile(fmt.Sprintf("table%d.yaml", i), []byte(builder.String()))
+	}
+	packages, errs := p.Parse()
+	require.Nil(t, errs)
+	return packages
+}
+
+func TestRenderDeterministic(t *testing.T) {
+	validator := require.New(t)
+	out := t.TempDir()
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(1))
+	expected, err := Render(schema(t, 20), out, nil, nil)
+	validator.Nil(err)
+	validator.Len(expected.Files(), 20)
+	runtime.GOMAXPROCS(8)
+	for i := 0; i < 5; i++ {
+		set, err := Render(schema(t, 20), out, nil, nil)
+		validator.Nil(err)
+		validator.Equal(expected.Files(), set.Files())
+	}
+}
+
+func BenchmarkRender(b *testing.B) {
+	for _, c := range []struct {
+		name  string
+		procs int
+	}{{"sequential", 1}, {"parallel", runtime.NumCPU()}} {
+		procs := c.procs
+		b.Run(c.name, func(b *testing.B) {
+			defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(procs))
+			out := b.TempDir()
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				packages := schema(b, 100)
+				b.StartTimer()
+				if _, err := Render(packages, out, nil, nil); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestRenderCacheMutual(t *testing.T) {
+	validator := require.New(t)
+	c, err := cache.Open(t.TempDir())
+	validator.Nil(err)
+	out := t.TempDir()
+	// 相互引用的包闭包相同，缓存不能串用
+	for i := 0; i < 2; i++ {
+		set, err := Render(mutual(t), out, c, nil)
+		validator.Nil(err)
+		files := make(map[string]string)
+		for _, file := range set.Files() {
+			files[filepath.Base(file.Path)] = string(file.Content)
+		}
+		validator.Contains(files["alpha.go"], "type Alpha struct")
+		validator.NotContains(files["alpha.go"], "type Beta struct")
+		validator.Contains(files["beta.go"], "type Beta struct")
+		validator.NotContains(files["beta.go"], "type Alpha struct")
+	}
+}
+
+// mutual 生成两个相互引用的表
+func mutual(t testing.TB) []*parser.Package {
+	p := parser.NewParser()
+	for _, c := range [][2]string{{"alpha", "beta"}, {"beta", "alpha"}} {
+		p.AddYamlFile(c[0]+".yaml", []byte(fmt.Sprintf(`version: v1
+kind: Model
+metadata:
+  name: %s
+spec:
+  # %s
+  %s:
+    id!: objectid # 主键
+    peer?: %s # 对端
+`, c[0], c[0], c[0], c[1])))
+	}
+	packages, errs := p.Parse()
+	require.Nil(t, errs)
+	return packages
+}
+
+func TestRenderOmitEmpty(t *testing.T) {
+	validator := require.New(t)
+	packages, errs := parser.NewParser().AddYamlFile("demo.yaml", []byte(`version: v1
+kind: Model
+metadata:
+  name: demo
+spec:
+  # 示例
+  demo:
+    id!: objectid # 主键
+    name: string # 名称
+    nickname?: string # 昵称
+    tags[]: string # 标签
+`)).Parse()
+	validator.Nil(errs)
+	set, err := Render(packages, t.TempDir(), nil, nil)
+	validator.Nil(err)
+	content := string(set.Files()[0].Content)
+	// 必填字段取零值时也要写入，以通过required校验
+	validator.Contains(content, "`bson:\"_id,omitempty\"`")
+	validator.Contains(content, "`bson:\"name\"`")
+	validator.Contains(content, "`bson:\"nickname,omitempty\"`")
+	validator.Contains(content, "`bson:\"tags,omitempty\"`")
+}
+
+func TestRenderDependency(t *testing.T) {
+	validator := require.New(t)
+	fsys := fstest.MapFS{
+		"app/windranger.yaml": {Data: []byte("version: v1\nkind: Windranger\nresources:\n  - user.yaml\ndependencies:\n  - name: base\n    path: ../base\n")},
+		"app/user.yaml": {Data: []byte(`version: v1
+kind: Model
+metadata:
+  name: user
+spec:
+  # 用户
+  user:
+    id!: string # 主键
+    gender: gender # 性别
+    addresses[]: address # 地址
+`)},
+		"base/windranger.yaml": {Data: []byte("version: v1\nkind: Windranger\nresources:\n  - common.yaml\n")},
+		"base/common.yaml": {Data: []byte(`version: v1
+kind: Model
+metadata:
+  name: common
+spec:
+  # 性别
+  gender:
+    - male # 男
+    - female # 女
+  # 地址
+  address:
+    city: string # 城市
+`)},
+	}
+	parse := func(dir string) []*parser.Package {
+		packages, errs := parser.NewParser().AddYamlFS(fsys, dir).Parse()
+		validator.Nil(errs)
+		return packages
+	}
+	root := t.TempDir()
+	_, err := Render(parse("app"), filepath.Join(root, "model"), nil, nil)
+	validator.ErrorContains(err, "请使用--dep-import base=<导入路径>")
+
+	// 依赖项目单独生成，本项目从其go包导入
+	base, err := Render(parse("base"), filepath.Join(root, "base"), nil, nil)
+	validator.Nil(err)
+	validator.Nil(base.Write())
+	set, err := Render(parse("app"), filepath.Join(root, "model"), nil, map[string]string{"base": "app/base"})
+	validator.Nil(err)
+	validator.Len(set.Files(), 1)
+	content := string(set.Files()[0].Content)
+	validator.Contains(content, `base "app/base"`)
+	validator.Contains(content, "Gender base.Gender")
+	validator.Contains(content, "Addresses []*base.Address")
+	validator.Nil(set.Write())
+
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("缺少go")
+	}
+	validator.Nil(os.WriteFile(filepath.Join(root, "go.mod"), []byte("module app\n\ngo 1.18\n"), os.ModePerm))
+	cmd := exec.Command("go", "build", "./...")
+	cmd.Dir = root
+	cmd.Env = append(os.Environ(), "GOFLAGS=-mod=mod", "GOPROXY=off", "GOWORK=off")
+	output, err := cmd.CombinedOutput()
+	validator.Nil(err, string(output))
+}
+
+func TestRenderDependencyShadowed(t *testing.T) {
+	validator := require.New(t)
+	fsys := fstest.MapFS{
+		"app/windranger.yaml": {Data: []byte("version: v1\nkind: Windranger\nresources:\n  - user.yaml\ndependencies:\n  - name: base\n    path: ../base\n")},
+		"app/user.yaml": {Data: []byte(`version: v1
+kind: Model
+metadata:
+  name: user
+spec:
+  # 地址
+  address:
+    street: string # 街道
+  # 用户
+  user:
+    id!: string # 主键
+    home: address # 住址
+`)},
+		"base/windranger.yaml": {Data: []byte("version: v1\nkind: Windranger\nresources:\n  - common.yaml\n")},
+		"base/common.yaml": {Data: []byte(`version: v1
+kind: Model
+metadata:
+  name: common
+spec:
+  # 地址
+  address:
+    city: string # 城市
+`)},
+	}
+	packages, errs := parser.NewParser().AddYamlFS(fsys, "app").Parse()
+	validator.Nil(errs)
+	// 本项目定义的同名类型优先，不需要导入依赖
+	set, err := Render(packages, t.TempDir(), nil, nil)
+	validator.Nil(err)
+	content := string(set.Files()[0].Content)
+	validator.Contains(content, "Home Address")
+	validator.NotContains(content, "base.")
+}
+
+func TestRenderShardKeyIndex(t *testing.T) {
+	validator := require.New(t)
+	render := func(indexes string) string {
+		packages, errs := parser.NewParser().AddYamlFile("demo.yaml", []byte(`version: v1
+kind: Model
+metadata:
+  name: demo
+  indexes:
+`+indexes+`  shard_key:
+    keys: [tenant, id]
+spec:
+  # 示例
+  demo:
+    id!: objectid # 主键
+    tenant: string # 租户
+`)).Parse()
+		validator.Nil(errs)
+		set, err := Render(packages, t.TempDir(), nil, nil)
+		validator.Nil(err)
+		return string(set.Files()[0].Content)
+	}
+	validator.Contains(render("    - keys: [tenant]\n"), "Keys:    Demo{}.ShardKey(),")
+	// 已声明同键的索引时不再创建未命名的分片键索引
+	validator.NotContains(render("    - keys: [tenant, id]\n      name: tenant_id\n      unique: true\n"), "Keys:    Demo{}.ShardKey(),")
+}
+
+func TestRenderEnumWithoutComment(t *testing.T) {
+	validator := require.New(t)
+	packages, errs := parser.NewParser().AddYamlFile("demo.yaml", []byte(`version: v1
+kind: Model
+metadata:
+  name: demo
+spec:
+  state:
+    - on
+    - off
+  # 示例
+  demo:
+    state: state # 状态
+`)).Parse()
+	validator.Nil(errs)
+	set, err := Render(packages, t.TempDir(), nil, nil)
+	validator.Nil(err)
+	content := string(set.Files()[0].Content)
+	// 没有注释的枚举同样声明类型
+	validator.Contains(content, "// windranger:end\n\ntype State int\nconst (")
+}
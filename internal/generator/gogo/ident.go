@@ -0,0 +1,11 @@
+package gogo
+
+import "github.com/wzyjerry/windranger/internal/util"
+
+// Ident go标识符策略，转义go保留字，拒绝非ASCII标识符
+var Ident = util.NewIdentPolicy(
+	"break", "case", "chan", "const", "continue", "default", "defer", "else",
+	"fallthrough", "for", "func", "go", "goto", "if", "import", "interface",
+	"map", "package", "range", "return", "select", "struct", "switch", "type",
+	"var",
+)
@@ -0,0 +1,85 @@
+package migrate
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"text/template"
+
+	"github.com/wzyjerry/windranger/internal/generator/gogo"
+	"github.com/wzyjerry/windranger/internal/migrate"
+	tmpl "github.com/wzyjerry/windranger/internal/template"
+	"github.com/wzyjerry/windranger/internal/util"
+)
+
+const (
+	// FormatMongosh 生成up/down两个mongosh脚本
+	FormatMongosh = "mongosh"
+	// FormatGo 生成包含Up/Down函数的go文件
+	FormatGo = "go"
+)
+
+// InfoMigrate 迁移模板信息
+type InfoMigrate struct {
+	// PackageName go文件包名
+	PackageName string
+	// Ident go函数名后缀
+	Ident string
+	// Collection 迁移记录集合
+	Collection string
+	// Direction 脚本方向，up或down
+	Direction string
+
+	Plan *migrate.Plan
+}
+
+// execute 渲染模板并写文件
+func execute(name string, info *InfoMigrate, filename string) error {
+	t, err := template.New("migrate").Funcs(util.FuncMap).ParseFS(tmpl.FS, path.Join("migrate", name))
+	if err != nil {
+		return err
+	}
+	buffer := bytes.NewBuffer(nil)
+	err = t.ExecuteTemplate(buffer, name, info)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, buffer.Bytes(), os.ModePerm)
+}
+
+// Generate 生成迁移脚本
+func Generate(plan *migrate.Plan, out string, format string) error {
+	// 准备生成目录
+	err := os.MkdirAll(out, os.ModePerm)
+	if err != nil {
+		return err
+	}
+	info := &InfoMigrate{
+		Collection: migrate.Collection,
+		Plan:       plan,
+	}
+	switch format {
+	case FormatMongosh:
+		for _, direction := range []string{"up", "down"} {
+			info.Direction = direction
+			err = execute("mongosh.tmpl", info, path.Join(out, plan.ID+"."+direction+".js"))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	case FormatGo:
+		_, folder := path.Split(out)
+		info.PackageName, err = gogo.Ident.Sanitize(util.Camel(folder))
+		if err != nil {
+			return err
+		}
+		info.Ident, err = gogo.Ident.Sanitize(util.ProtoPascal(plan.ID))
+		if err != nil {
+			return err
+		}
+		return execute("go.tmpl", info, path.Join(out, plan.ID+".go"))
+	}
+	return fmt.Errorf("未知迁移格式: %s", format)
+}
@@ -0,0 +1,95 @@
+package mongo
+
+import (
+	"bytes"
+	"encoding/json"
+	"path"
+	"text/template"
+
+	"github.com/wzyjerry/windranger/internal/cache"
+	"github.com/wzyjerry/windranger/internal/ir"
+	"github.com/wzyjerry/windranger/internal/output"
+	"github.com/wzyjerry/windranger/internal/parser"
+	tmpl "github.com/wzyjerry/windranger/internal/template"
+	"github.com/wzyjerry/windranger/internal/util"
+)
+
+// InfoMongo mongosh脚本模板信息
+type InfoMongo struct {
+	// Collection 集合元数据
+	Collection *parser.Collection
+	// Validator $jsonSchema校验器，已格式化为JSON
+	Validator string
+}
+
+// Render 为本项目的每个表渲染mongosh初始化脚本
+//
+// c不为nil时按表及其引用的包的哈希缓存渲染结果，只重新渲染受影响的表
+func Render(packages []*parser.Package, out string, c *cache.Cache) (*output.Set, error) {
+	r := newResolver(packages)
+	set := output.NewSet()
+	// 准备模板
+	name := "mongo.tmpl"
+	source, err := tmpl.FS.ReadFile(path.Join("mongo", name))
+	if err != nil {
+		return nil, err
+	}
+	t, err := template.New("mongo").Funcs(util.FuncMap).ParseFS(tmpl.FS, path.Join("mongo", name))
+	if err != nil {
+		return nil, err
+	}
+	// 依赖项目的表只供引用
+	for _, pack := range parser.Own(packages) {
+		if pack.Collection == nil {
+			continue
+		}
+		filename := path.Join(out, util.Camel(pack.Name)+".js")
+		// 命中缓存时跳过渲染，相互引用的包闭包相同，键中需要包含包名
+		key := cache.Key("mongo", string(source), pack.Name, ir.Hash(cache.Closure(packages, pack)))
+		if rendered, ok := c.Get(cache.KindRender, key); ok {
+			set.Add(filename, rendered)
+			continue
+		}
+		var table *parser.Structure
+		for _, structure := range pack.Structures {
+			if structure.Name == pack.Name {
+				table = structure
+			}
+		}
+		schema, err := r.structure(table)
+		if err != nil {
+			return nil, err
+		}
+		validator, err := json.MarshalIndent(map[string]any{
+			"$jsonSchema": schema,
+		}, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		// 准备生成信息
+		info := &InfoMongo{
+			Collection: pack.Collection,
+			Validator:  string(validator),
+		}
+		// 生成
+		buffer := bytes.NewBuffer(nil)
+		err = t.ExecuteTemplate(buffer, name, info)
+		if err != nil {
+			return nil, err
+		}
+		if err := c.Put(cache.KindRender, key, buffer.Bytes()); err != nil {
+			return nil, err
+		}
+		set.Add(filename, buffer.Bytes())
+	}
+	return set, nil
+}
+
+// Generate 为每个表生成mongosh初始化脚本
+func Generate(packages []*parser.Package, out string) error {
+	set, err := Render(packages, out, nil)
+	if err != nil {
+		return err
+	}
+	return set.Write()
+}
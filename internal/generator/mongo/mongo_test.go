@@ -0,0 +1,47 @@
+package mongo
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/wzyjerry/windranger/internal/cache"
+	"github.com/wzyjerry/windranger/internal/parser"
+)
+
+func TestRenderCacheMutual(t *testing.T) {
+	validator := require.New(t)
+	c, err := cache.Open(t.TempDir())
+	validator.Nil(err)
+	// 通过枚举相互引用的包闭包相同，缓存不能串用
+	for i := 0; i < 2; i++ {
+		p := parser.NewParser()
+		for _, c := range [][2]string{{"alpha", "beta"}, {"beta", "alpha"}} {
+			p.AddYamlFile(c[0]+".yaml", []byte(fmt.Sprintf(`version: v1
+kind: Model
+metadata:
+  name: %s
+spec:
+  # 状态
+  %s_state:
+    - on # 开
+    - off # 关
+  # %s
+  %s:
+    id!: objectid # 主键
+    peer_state: %s_state # 对端状态
+`, c[0], c[0], c[0], c[0], c[1])))
+		}
+		packages, errs := p.Parse()
+		validator.Nil(errs)
+		set, err := Render(packages, t.TempDir(), c)
+		validator.Nil(err)
+		files := make(map[string]string)
+		for _, file := range set.Files() {
+			files[filepath.Base(file.Path)] = string(file.Content)
+		}
+		validator.Contains(files["alpha.js"], `createCollection("alpha"`)
+		validator.Contains(files["beta.js"], `createCollection("beta"`)
+	}
+}
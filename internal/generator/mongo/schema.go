@@ -0,0 +1,168 @@
+package mongo
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/wzyjerry/windranger/internal/parser"
+)
+
+// bsonTypes 基本类型对应的bsonType
+var bsonTypes = map[string]string{
+	"int":      "long",
+	"float":    "double",
+	"bool":     "bool",
+	"string":   "string",
+	"datetime": "date",
+	"objectid": "objectId",
+}
+
+// JSONSchema MongoDB $jsonSchema子集
+type JSONSchema struct {
+	BSONType    any                    `json:"bsonType,omitempty"`
+	Description string                 `json:"description,omitempty"`
+	Required    []string               `json:"required,omitempty"`
+	Properties  map[string]*JSONSchema `json:"properties,omitempty"`
+	Items       *JSONSchema            `json:"items,omitempty"`
+	Enum        []any                  `json:"enum,omitempty"`
+}
+
+// resolver 类型解析器
+type resolver struct {
+	enums      map[string]*parser.Enum
+	structures map[string]*parser.Structure
+	// visiting 正在展开的结构，用于检测循环引用
+	visiting map[string]struct{}
+}
+
+func newResolver(packages []*parser.Package) *resolver {
+	r := &resolver{
+		enums:      make(map[string]*parser.Enum),
+		structures: make(map[string]*parser.Structure),
+		visiting:   make(map[string]struct{}),
+	}
+	for _, pack := range packages {
+		for _, enum := range pack.Enums {
+			r.enums[enum.Name] = enum
+		}
+		for _, structure := range pack.Structures {
+			r.structures[structure.Name] = structure
+		}
+	}
+	return r
+}
+
+// description 合并注释和废弃标记
+//
+// MongoDB的$jsonSchema不支持deprecated关键字，废弃信息写入description
+func description(comment string, deprecated *parser.Deprecation) string {
+	if deprecated == nil {
+		return comment
+	}
+	result := "Deprecated"
+	if reason := deprecated.String(); reason != "" {
+		result += ": " + reason
+	}
+	if comment != "" {
+		result = comment + " (" + result + ")"
+	}
+	return result
+}
+
+// structure 生成结构的schema
+func (r *resolver) structure(structure *parser.Structure) (*JSONSchema, error) {
+	if _, ok := r.visiting[structure.Name]; ok {
+		return nil, fmt.Errorf("结构循环引用: %s", structure.Name)
+	}
+	r.visiting[structure.Name] = struct{}{}
+	defer delete(r.visiting, structure.Name)
+	schema := &JSONSchema{
+		BSONType:    "object",
+		Description: description(structure.Comment, structure.Deprecated),
+		Required:    make([]string, 0),
+		Properties:  make(map[string]*JSONSchema, len(structure.Fields)),
+	}
+	for _, field := range structure.Fields {
+		key := field.Name
+		if field.Type.Kind == parser.KindPrimaryKey {
+			key = "_id"
+		}
+		property, err := r.field(field)
+		if err != nil {
+			return nil, err
+		}
+		schema.Properties[key] = property
+		if field.Type.Kind == parser.KindNormal || field.Type.Kind == parser.KindPrimaryKey {
+			schema.Required = append(schema.Required, key)
+		}
+	}
+	return schema, nil
+}
+
+// element 生成字段元素类型的schema
+func (r *resolver) element(raw string) (*JSONSchema, error) {
+	if bsonType, ok := bsonTypes[raw]; ok {
+		return &JSONSchema{
+			BSONType: bsonType,
+		}, nil
+	}
+	if enum, ok := r.enums[raw]; ok {
+		// go后端以iota顺序存储枚举值
+		values := make([]any, len(enum.EnumFields))
+		names := make([]string, len(enum.EnumFields))
+		for i, field := range enum.EnumFields {
+			values[i] = i
+			names[i] = fmt.Sprintf("%d=%s", i, field.Name)
+		}
+		return &JSONSchema{
+			BSONType:    []string{"int", "long"},
+			Description: description(strings.Join(names, ", "), enum.Deprecated),
+			Enum:        values,
+		}, nil
+	}
+	if structure, ok := r.structures[raw]; ok {
+		return r.structure(structure)
+	}
+	return nil, fmt.Errorf("未知类型: %s", raw)
+}
+
+// field 生成字段的schema
+func (r *resolver) field(field *parser.Field) (*JSONSchema, error) {
+	element, err := r.element(field.Type.Raw)
+	if err != nil {
+		return nil, err
+	}
+	comment := description(field.Comment, field.Deprecated)
+	switch field.Type.Kind {
+	case parser.KindArray:
+		return &JSONSchema{
+			BSONType:    "array",
+			Description: comment,
+			Items:       element,
+		}, nil
+	case parser.KindOptional:
+		element.BSONType = nullable(element.BSONType)
+		if element.Enum != nil {
+			element.Enum = append(element.Enum, nil)
+		}
+	}
+	switch {
+	case comment == "":
+	case element.Enum != nil:
+		element.Description = comment + ": " + element.Description
+	default:
+		element.Description = comment
+	}
+	return element, nil
+}
+
+// nullable 允许bsonType为null
+func nullable(bsonType any) any {
+	switch t := bsonType.(type) {
+	case string:
+		return []string{t, "null"}
+	case []string:
+		return append(t, "null")
+	}
+	return bsonType
+}
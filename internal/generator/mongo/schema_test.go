@@ -0,0 +1,69 @@
+package mongo
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/wzyjerry/windranger/internal/parser"
+)
+
+func TestStructureSchema(t *testing.T) {
+	validator := require.New(t)
+	r := newResolver([]*parser.Package{{
+		Enums: []*parser.Enum{{
+			Name:       "gender",
+			EnumFields: []*parser.EnumField{{Name: "male"}, {Name: "female"}},
+		}},
+		Structures: []*parser.Structure{{
+			Name: "demo",
+			Fields: []*parser.Field{{
+				Name: "id",
+				Type: &parser.Type{Raw: "objectid", Kind: parser.KindPrimaryKey},
+			}, {
+				Name:    "gender",
+				Comment: "性别",
+				Type:    &parser.Type{Raw: "gender", Kind: parser.KindOptional},
+			}, {
+				Name:       "tags",
+				Type:       &parser.Type{Raw: "string", Kind: parser.KindArray},
+				Deprecated: &parser.Deprecation{Replacement: "labels"},
+			}},
+		}},
+	}})
+	schema, err := r.element("demo")
+	validator.Nil(err)
+	validator.Equal(&JSONSchema{
+		BSONType: "object",
+		Required: []string{"_id"},
+		Properties: map[string]*JSONSchema{
+			"_id": {BSONType: "objectId"},
+			"gender": {
+				BSONType:    []string{"int", "long", "null"},
+				Description: "性别: 0=male, 1=female",
+				Enum:        []any{0, 1, nil},
+			},
+			"tags": {
+				BSONType:    "array",
+				Description: "Deprecated: 使用labels替代",
+				Items:       &JSONSchema{BSONType: "string"},
+			},
+		},
+	}, schema)
+}
+
+func TestStructureSchemaCycle(t *testing.T) {
+	validator := require.New(t)
+	r := newResolver([]*parser.Package{{
+		Structures: []*parser.Structure{{
+			Name: "node",
+			Fields: []*parser.Field{{
+				Name: "next",
+				Type: &parser.Type{Raw: "node", Kind: parser.KindOptional},
+			}},
+		}},
+	}})
+	_, err := r.element("node")
+	validator.NotNil(err)
+	_, err = r.element("unknown")
+	validator.NotNil(err)
+}
@@ -0,0 +1,33 @@
+package ir
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+
+	"github.com/wzyjerry/windranger/internal/parser"
+)
+
+// Hash 模型内容的sha256，忽略源码位置，同一模型从不同路径加载时结果相同
+func Hash(packages []*parser.Package) string {
+	doc := NewDocument(packages, "")
+	for _, pack := range doc.Packages {
+		for _, enum := range pack.Enums {
+			enum.Pos = nil
+			for _, value := range enum.Values {
+				value.Pos = nil
+			}
+		}
+		for _, structure := range pack.Structures {
+			structure.Pos = nil
+			for _, field := range structure.Fields {
+				field.Pos = nil
+				field.Type.Pos = nil
+			}
+		}
+	}
+	// 文档只包含可序列化的基本类型，不会出错
+	content, _ := json.Marshal(doc)
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,471 @@
+package ir
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/wzyjerry/windranger/internal/parser"
+)
+
+// Version IR格式版本，格式发生不兼容变更时递增
+const Version = "v1"
+
+const (
+	// StageParsed 解析后、链接前，Ident、Type.Name和Dependencies为空
+	StageParsed = "parsed"
+	// StageLinked 链接后，Ident、Type.Name和Dependencies由后端链接器填充
+	StageLinked = "linked"
+)
+
+// Document IR文档，格式说明见document/ir.md
+type Document struct {
+	Version string `json:"version"`
+	// Stage parsed或linked
+	Stage string `json:"stage"`
+	// Linker 链接所用后端，仅linked阶段非空
+	Linker   string     `json:"linker,omitempty"`
+	Packages []*Package `json:"packages"`
+}
+
+// Position 源码位置，行列从1开始
+type Position struct {
+	File   string `json:"file,omitempty"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+}
+
+type Deprecation struct {
+	Reason      string `json:"reason,omitempty"`
+	Replacement string `json:"replacement,omitempty"`
+}
+
+type Type struct {
+	Raw     string    `json:"raw"`
+	Name    string    `json:"name,omitempty"`
+	Kind    string    `json:"kind"`
+	Package string    `json:"package,omitempty"`
+	Pos     *Position `json:"pos,omitempty"`
+}
+
+type Field struct {
+	Name       string       `json:"name"`
+	Ident      string       `json:"ident,omitempty"`
+	Comment    string       `json:"comment,omitempty"`
+	Type       *Type        `json:"type"`
+	Default    *string      `json:"default,omitempty"`
+	Deprecated *Deprecation `json:"deprecated,omitempty"`
+	Aliases    []string     `json:"aliases,omitempty"`
+	Suppress   []string     `json:"suppress,omitempty"`
+	Pos        *Position    `json:"pos,omitempty"`
+}
+
+type Structure struct {
+	Name       string       `json:"name"`
+	Ident      string       `json:"ident,omitempty"`
+	Comment    string       `json:"comment,omitempty"`
+	Fields     []*Field     `json:"fields"`
+	Deprecated *Deprecation `json:"deprecated,omitempty"`
+	Suppress   []string     `json:"suppress,omitempty"`
+	Pos        *Position    `json:"pos,omitempty"`
+}
+
+type EnumField struct {
+	Name       string       `json:"name"`
+	Ident      string       `json:"ident,omitempty"`
+	Comment    string       `json:"comment,omitempty"`
+	Deprecated *Deprecation `json:"deprecated,omitempty"`
+	Suppress   []string     `json:"suppress,omitempty"`
+	Pos        *Position    `json:"pos,omitempty"`
+}
+
+type Enum struct {
+	Name       string       `json:"name"`
+	Ident      string       `json:"ident,omitempty"`
+	Comment    string       `json:"comment,omitempty"`
+	Values     []*EnumField `json:"values"`
+	Deprecated *Deprecation `json:"deprecated,omitempty"`
+	Suppress   []string     `json:"suppress,omitempty"`
+	Pos        *Position    `json:"pos,omitempty"`
+}
+
+type IndexKey struct {
+	Field string `json:"field"`
+	Key   string `json:"key"`
+	Order string `json:"order"`
+}
+
+type Index struct {
+	Name        string      `json:"name"`
+	Keys        []*IndexKey `json:"keys"`
+	Unique      bool        `json:"unique,omitempty"`
+	ExpireAfter *int32      `json:"expire_after,omitempty"`
+}
+
+type Collection struct {
+	Name     string      `json:"name"`
+	Database string      `json:"database,omitempty"`
+	Indexes  []*Index    `json:"indexes,omitempty"`
+	ShardKey []*IndexKey `json:"shard_key,omitempty"`
+}
+
+type Package struct {
+	Name         string       `json:"name"`
+	Dependencies []string     `json:"dependencies"`
+	Enums        []*Enum      `json:"enums"`
+	Structures   []*Structure `json:"structures"`
+	Collection   *Collection  `json:"collection,omitempty"`
+	// Module 所属的依赖项目，本项目的包为空
+	Module string `json:"module,omitempty"`
+}
+
+var kindName = [...]string{
+	parser.KindNormal:     "normal",
+	parser.KindArray:      "array",
+	parser.KindOptional:   "optional",
+	parser.KindPrimaryKey: "primary_key",
+}
+
+var orderName = [...]string{
+	parser.IndexAscending:  "asc",
+	parser.IndexDescending: "desc",
+	parser.IndexText:       "text",
+	parser.IndexHashed:     "hashed",
+}
+
+// NewDocument 构建IR文档，linker为链接所用后端，为空表示未链接
+func NewDocument(packages []*parser.Package, linker string) *Document {
+	doc := &Document{
+		Version:  Version,
+		Stage:    StageParsed,
+		Linker:   linker,
+		Packages: make([]*Package, len(packages)),
+	}
+	if linker != "" {
+		doc.Stage = StageLinked
+	}
+	for i, pack := range packages {
+		doc.Packages[i] = fromPackage(pack)
+	}
+	return doc
+}
+
+// Encode 将包序列化为IR文档，linker为链接所用后端，为空表示未链接
+func Encode(w io.Writer, packages []*parser.Package, linker string) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(NewDocument(packages, linker))
+}
+
+// Decode 读取IR文档并还原为包
+func Decode(r io.Reader) (*Document, []*parser.Package, error) {
+	doc := new(Document)
+	if err := json.NewDecoder(r).Decode(doc); err != nil {
+		return nil, nil, err
+	}
+	packages, err := doc.Restore()
+	if err != nil {
+		return nil, nil, err
+	}
+	return doc, packages, nil
+}
+
+// Restore 校验版本并还原为包
+func (doc *Document) Restore() ([]*parser.Package, error) {
+	if doc.Version != Version {
+		return nil, fmt.Errorf("不支持的IR版本: %s", doc.Version)
+	}
+	if doc.Stage != StageParsed && doc.Stage != StageLinked {
+		return nil, fmt.Errorf("未知IR阶段: %s", doc.Stage)
+	}
+	packages := make([]*parser.Package, len(doc.Packages))
+	for i, pack := range doc.Packages {
+		p, err := toPackage(pack)
+		if err != nil {
+			return nil, err
+		}
+		packages[i] = p
+	}
+	return packages, nil
+}
+
+func fromPosition(pos parser.Position) *Position {
+	if !pos.IsValid() && pos.File == "" {
+		return nil
+	}
+	return &Position{
+		File:   pos.File,
+		Line:   pos.Line,
+		Column: pos.Column,
+	}
+}
+
+func toPosition(pos *Position) parser.Position {
+	if pos == nil {
+		return parser.Position{}
+	}
+	return parser.Position{
+		File:   pos.File,
+		Line:   pos.Line,
+		Column: pos.Column,
+	}
+}
+
+func fromDeprecation(d *parser.Deprecation) *Deprecation {
+	if d == nil {
+		return nil
+	}
+	return &Deprecation{
+		Reason:      d.Reason,
+		Replacement: d.Replacement,
+	}
+}
+
+func toDeprecation(d *Deprecation) *parser.Deprecation {
+	if d == nil {
+		return nil
+	}
+	return &parser.Deprecation{
+		Reason:      d.Reason,
+		Replacement: d.Replacement,
+	}
+}
+
+// parseName 按名称表查找枚举值
+func parseName(names []string, name string, what string) (uint32, error) {
+	for i, n := range names {
+		if n == name {
+			return uint32(i), nil
+		}
+	}
+	return 0, fmt.Errorf("未知%s: %s", what, name)
+}
+
+func fromIndexKeys(keys []*parser.IndexKey) []*IndexKey {
+	result := make([]*IndexKey, len(keys))
+	for i, key := range keys {
+		result[i] = &IndexKey{
+			Field: key.Field,
+			Key:   key.Key,
+			Order: orderName[key.Order],
+		}
+	}
+	return result
+}
+
+func toIndexKeys(keys []*IndexKey) ([]*parser.IndexKey, error) {
+	result := make([]*parser.IndexKey, len(keys))
+	for i, key := range keys {
+		order, err := parseName(orderName[:], key.Order, "索引顺序")
+		if err != nil {
+			return nil, err
+		}
+		result[i] = &parser.IndexKey{
+			Field: key.Field,
+			Key:   key.Key,
+			Order: parser.IndexOrder(order),
+		}
+	}
+	return result, nil
+}
+
+func fromPackage(pack *parser.Package) *Package {
+	result := &Package{
+		Name:         pack.Name,
+		Module:       pack.Module,
+		Dependencies: pack.Dependencies,
+		Enums:        make([]*Enum, len(pack.Enums)),
+		Structures:   make([]*Structure, len(pack.Structures)),
+	}
+	if result.Dependencies == nil {
+		result.Dependencies = make([]string, 0)
+	}
+	for i, enum := range pack.Enums {
+		values := make([]*EnumField, len(enum.EnumFields))
+		for j, field := range enum.EnumFields {
+			values[j] = &EnumField{
+				Name:       field.Name,
+				Ident:      field.Ident,
+				Comment:    field.Comment,
+				Deprecated: fromDeprecation(field.Deprecated),
+				Suppress:   field.Suppress,
+				Pos:        fromPosition(field.Pos),
+			}
+		}
+		result.Enums[i] = &Enum{
+			Name:       enum.Name,
+			Ident:      enum.Ident,
+			Comment:    enum.Comment,
+			Values:     values,
+			Deprecated: fromDeprecation(enum.Deprecated),
+			Suppress:   enum.Suppress,
+			Pos:        fromPosition(enum.Pos),
+		}
+	}
+	for i, structure := range pack.Structures {
+		fields := make([]*Field, len(structure.Fields))
+		for j, field := range structure.Fields {
+			fields[j] = &Field{
+				Name:    field.Name,
+				Ident:   field.Ident,
+				Comment: field.Comment,
+				Type: &Type{
+					Raw:     field.Type.Raw,
+					Name:    field.Type.Name,
+					Kind:    kindName[field.Type.Kind],
+					Package: field.Type.Package,
+					Pos:     fromPosition(field.Type.Pos),
+				},
+				Default:    field.Default,
+				Deprecated: fromDeprecation(field.Deprecated),
+				Aliases:    field.Aliases,
+				Suppress:   field.Suppress,
+				Pos:        fromPosition(field.Pos),
+			}
+		}
+		result.Structures[i] = &Structure{
+			Name:       structure.Name,
+			Ident:      structure.Ident,
+			Comment:    structure.Comment,
+			Fields:     fields,
+			Deprecated: fromDeprecation(structure.Deprecated),
+			Suppress:   structure.Suppress,
+			Pos:        fromPosition(structure.Pos),
+		}
+	}
+	if c := pack.Collection; c != nil {
+		result.Collection = &Collection{
+			Name:     c.Name,
+			Database: c.Database,
+			Indexes:  make([]*Index, len(c.Indexes)),
+		}
+		for i, index := range c.Indexes {
+			result.Collection.Indexes[i] = &Index{
+				Name:        index.Name,
+				Keys:        fromIndexKeys(index.Keys),
+				Unique:      index.Unique,
+				ExpireAfter: index.ExpireAfter,
+			}
+		}
+		if c.ShardKey != nil {
+			result.Collection.ShardKey = fromIndexKeys(c.ShardKey.Keys)
+		}
+	}
+	return result
+}
+
+func toPackage(pack *Package) (*parser.Package, error) {
+	result := &parser.Package{
+		Name:         pack.Name,
+		Module:       pack.Module,
+		Dependencies: pack.Dependencies,
+		Enums:        make([]*parser.Enum, len(pack.Enums)),
+		Structures:   make([]*parser.Structure, len(pack.Structures)),
+	}
+	if result.Dependencies == nil {
+		result.Dependencies = make([]string, 0)
+	}
+	for i, enum := range pack.Enums {
+		fields := make([]*parser.EnumField, len(enum.Values))
+		for j, field := range enum.Values {
+			fields[j] = &parser.EnumField{
+				Name:       field.Name,
+				Comment:    field.Comment,
+				Deprecated: toDeprecation(field.Deprecated),
+				Suppress:   field.Suppress,
+				Ident:      field.Ident,
+				Pos:        toPosition(field.Pos),
+			}
+		}
+		result.Enums[i] = &parser.Enum{
+			Name:       enum.Name,
+			Comment:    enum.Comment,
+			EnumFields: fields,
+			Deprecated: toDeprecation(enum.Deprecated),
+			Suppress:   enum.Suppress,
+			Ident:      enum.Ident,
+			Pos:        toPosition(enum.Pos),
+		}
+	}
+	for i, structure := range pack.Structures {
+		fields := make([]*parser.Field, len(structure.Fields))
+		for j, field := range structure.Fields {
+			if field.Type == nil {
+				return nil, fmt.Errorf("字段缺少类型: %s.%s", structure.Name, field.Name)
+			}
+			kind, err := parseName(kindName[:], field.Type.Kind, "字段类型")
+			if err != nil {
+				return nil, err
+			}
+			fields[j] = &parser.Field{
+				Name:    field.Name,
+				Comment: field.Comment,
+				Type: &parser.Type{
+					Raw:     field.Type.Raw,
+					Name:    field.Type.Name,
+					Kind:    parser.Kind(kind),
+					Package: field.Type.Package,
+					Pos:     toPosition(field.Type.Pos),
+				},
+				Default:    field.Default,
+				Deprecated: toDeprecation(field.Deprecated),
+				Aliases:    field.Aliases,
+				Suppress:   field.Suppress,
+				Ident:      field.Ident,
+				Pos:        toPosition(field.Pos),
+			}
+		}
+		result.Structures[i] = &parser.Structure{
+			Name:       structure.Name,
+			Comment:    structure.Comment,
+			Fields:     fields,
+			Deprecated: toDeprecation(structure.Deprecated),
+			Suppress:   structure.Suppress,
+			Ident:      structure.Ident,
+			Pos:        toPosition(structure.Pos),
+		}
+	}
+	if c := pack.Collection; c != nil {
+		result.Collection = &parser.Collection{
+			Name:     c.Name,
+			Database: c.Database,
+			Indexes:  make([]*parser.Index, len(c.Indexes)),
+		}
+		for i, index := range c.Indexes {
+			keys, err := toIndexKeys(index.Keys)
+			if err != nil {
+				return nil, err
+			}
+			result.Collection.Indexes[i] = &parser.Index{
+				Name:        index.Name,
+				Keys:        keys,
+				Unique:      index.Unique,
+				ExpireAfter: index.ExpireAfter,
+			}
+		}
+		if c.ShardKey != nil {
+			keys, err := toIndexKeys(c.ShardKey)
+			if err != nil {
+				return nil, err
+			}
+			result.Collection.ShardKey = &parser.ShardKey{
+				Keys: keys,
+			}
+		}
+	}
+	return result, nil
+}
+
+// MarshalPackage 序列化单个包
+func MarshalPackage(pack *parser.Package) ([]byte, error) {
+	return json.Marshal(fromPackage(pack))
+}
+
+// UnmarshalPackage 还原MarshalPackage序列化的包
+func UnmarshalPackage(content []byte) (*parser.Package, error) {
+	pack := new(Package)
+	if err := json.Unmarshal(content, pack); err != nil {
+		return nil, err
+	}
+	return toPackage(pack)
+}
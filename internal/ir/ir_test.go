@@ -0,0 +1,111 @@
+package ir_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/wzyjerry/windranger/internal/generator/gogo"
+	"github.com/wzyjerry/windranger/internal/ir"
+	"github.com/wzyjerry/windranger/internal/parser"
+)
+
+const model = `version: v2
+kind: Model
+metadata:
+  name: demo
+  indexes:
+    - keys: [name, -created_at]
+      unique: true
+  ttl:
+    field: created_at
+    expire_after: 3600
+  shard_key:
+    keys: [id]
+    hashed: true
+spec:
+  # 示例
+  demo:
+    id!: objectid # 主键
+    name: string # 名称
+    created_at: datetime # 创建时间
+    heading:
+      type: string
+      description: 标题
+      default: untitled
+      was: title
+      deprecated: {reason: 不再使用, replacement: name}
+    # windranger:ignore plural
+    tags[]: string # 标签
+    status: # 状态
+      - active # 启用
+      - inactive # 停用
+`
+
+func parse(t *testing.T) []*parser.Package {
+	packages, errs := parser.NewParser().AddYamlFile("demo.yaml", []byte(model)).Parse()
+	require.Nil(t, errs)
+	return packages
+}
+
+func TestRoundTrip(t *testing.T) {
+	validator := require.New(t)
+	buffer := bytes.NewBuffer(nil)
+	validator.Nil(ir.Encode(buffer, parse(t), ""))
+	doc, packages, err := ir.Decode(buffer)
+	validator.Nil(err)
+	validator.Equal(ir.StageParsed, doc.Stage)
+	validator.Equal(parse(t), packages)
+
+	linked, errs := gogo.Link(parse(t))
+	validator.Nil(errs)
+	buffer.Reset()
+	validator.Nil(ir.Encode(buffer, linked, "go"))
+	doc, packages, err = ir.Decode(buffer)
+	validator.Nil(err)
+	validator.Equal(ir.StageLinked, doc.Stage)
+	validator.Equal("go", doc.Linker)
+	validator.Equal(linked, packages)
+}
+
+func TestEncode(t *testing.T) {
+	validator := require.New(t)
+	buffer := bytes.NewBuffer(nil)
+	validator.Nil(ir.Encode(buffer, parse(t), ""))
+	content := buffer.String()
+	validator.Contains(content, `"version": "v1"`)
+	validator.Contains(content, `"kind": "primary_key"`)
+	validator.Contains(content, `"order": "hashed"`)
+	validator.Contains(content, `"suppress": [
+                "plural"
+              ]`)
+	validator.Contains(content, `"pos": {
+                "file": "demo.yaml",
+                "line": 17,
+                "column": 5
+              }`)
+}
+
+func TestDecodeFault(t *testing.T) {
+	validator := require.New(t)
+	_, _, err := ir.Decode(strings.NewReader(`{"version": "v0", "stage": "parsed"}`))
+	validator.EqualError(err, "不支持的IR版本: v0")
+	_, _, err = ir.Decode(strings.NewReader(`{"version": "v1", "stage": "rendered"}`))
+	validator.EqualError(err, "未知IR阶段: rendered")
+	_, _, err = ir.Decode(strings.NewReader(`{"version": "v1", "stage": "parsed", "packages": [
+		{"name": "demo", "structures": [{"name": "demo", "fields": [{"name": "id", "type": {"raw": "int", "kind": "pointer"}}]}]}
+	]}`))
+	validator.EqualError(err, "未知字段类型: pointer")
+}
+
+func TestHash(t *testing.T) {
+	validator := require.New(t)
+	moved, errs := parser.NewParser().AddYamlFile("model/demo.yaml", []byte("\n"+model)).Parse()
+	validator.Nil(errs)
+	validator.Equal(ir.Hash(parse(t)), ir.Hash(moved))
+	changed, errs := parser.NewParser().AddYamlFile("demo.yaml", []byte(strings.Replace(model, "# 名称", "# 姓名", 1))).Parse()
+	validator.Nil(errs)
+	validator.NotEqual(ir.Hash(parse(t)), ir.Hash(changed))
+	validator.Len(ir.Hash(parse(t)), 64)
+}
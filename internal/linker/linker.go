@@ -1,10 +1,13 @@
 package linker
 
 import (
+	"context"
+	"fmt"
 	"sort"
 	"strings"
 
 	"github.com/wzyjerry/windranger/internal/parser"
+	"github.com/wzyjerry/windranger/internal/util"
 )
 
 type FieldFunc func(string) string
@@ -13,12 +16,14 @@ type linker struct {
 	typemap   map[string]*parser.Type
 	packages  []*parser.Package
 	fieldFunc FieldFunc
+	policy    *util.IdentPolicy
 	errors    []error
 }
 
 func NewLinker() *linker {
 	return &linker{
 		typemap: make(map[string]*parser.Type),
+		policy:  util.NewIdentPolicy(),
 		errors:  make([]error, 0),
 	}
 }
@@ -41,23 +46,70 @@ func (l *linker) SetFieldFunc(f FieldFunc) *linker {
 	return l
 }
 
+// SetIdentPolicy 设置目标语言标识符策略
+func (l *linker) SetIdentPolicy(policy *util.IdentPolicy) *linker {
+	l.policy = policy
+	return l
+}
+
+// ident 按字段函数和标识符策略生成标识符
+func (l *linker) ident(name string, pos parser.Position) string {
+	ident, err := l.policy.Sanitize(l.fieldFunc(name))
+	if err != nil {
+		l.errors = append(l.errors, &parser.Error{
+			Pos: pos,
+			Err: fmt.Errorf("%w (%s)", err, name),
+		})
+	}
+	return ident
+}
+
+// typeIdent 生成引用类型标识符，非法名称已在类型定义处报告
+func (l *linker) typeIdent(raw string) string {
+	ident, _ := l.policy.Sanitize(l.fieldFunc(raw))
+	return ident
+}
+
+// enumIdent 生成枚举值标识符
+func (l *linker) enumIdent(enum string, field string, pos parser.Position) string {
+	ident, err := l.policy.Sanitize(strings.ToUpper(enum + "_" + field))
+	if err != nil {
+		l.errors = append(l.errors, &parser.Error{
+			Pos: pos,
+			Err: fmt.Errorf("%w (%s.%s)", err, enum, field),
+		})
+	}
+	return ident
+}
+
 func (l *linker) Link() ([]*parser.Package, []error) {
+	return l.LinkContext(context.Background())
+}
+
+// LinkContext 与Link相同，ctx结束后停止链接并返回ctx.Err()
+func (l *linker) LinkContext(ctx context.Context) ([]*parser.Package, []error) {
 	for _, pack := range l.packages {
+		if err := ctx.Err(); err != nil {
+			return nil, []error{err}
+		}
 		for _, enum := range pack.Enums {
+			enum.Ident = l.ident(enum.Name, enum.Pos)
 			for _, field := range enum.EnumFields {
-				field.Name = strings.ToUpper(enum.Name + "_" + field.Name)
+				field.Ident = l.enumIdent(enum.Name, field.Name, field.Pos)
 			}
 		}
 		depSet := make(map[string]struct{})
 		for _, structure := range pack.Structures {
+			structure.Ident = l.ident(structure.Name, structure.Pos)
 			for _, field := range structure.Fields {
+				field.Ident = l.ident(field.Name, field.Pos)
 				raw := field.Type.Raw
 				if t, ok := l.typemap[raw]; ok {
 					field.Type.Name = t.Name
 					field.Type.Package = t.Package
 					depSet[t.Package] = struct{}{}
 				} else {
-					field.Type.Name = l.fieldFunc(raw)
+					field.Type.Name = l.typeIdent(raw)
 				}
 			}
 		}
@@ -70,5 +122,8 @@ func (l *linker) Link() ([]*parser.Package, []error) {
 			return pack.Dependencies[i] < pack.Dependencies[j]
 		})
 	}
-	return l.packages, l.errors
+	if len(l.errors) != 0 {
+		return nil, l.errors
+	}
+	return l.packages, nil
 }
@@ -0,0 +1,64 @@
+package linker
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/wzyjerry/windranger/internal/parser"
+	"github.com/wzyjerry/windranger/internal/util"
+)
+
+func TestLinkIdent(t *testing.T) {
+	validator := require.New(t)
+	packages := []*parser.Package{{
+		Name: "demo",
+		Enums: []*parser.Enum{{
+			Name:       "type",
+			EnumFields: []*parser.EnumField{{Name: "range"}},
+		}},
+		Structures: []*parser.Structure{{
+			Name: "demo",
+			Fields: []*parser.Field{{
+				Name: "type",
+				Type: &parser.Type{Raw: "type"},
+			}, {
+				Name: "func",
+				Type: &parser.Type{Raw: "string"},
+			}},
+		}},
+	}}
+	packages, errs := NewLinker().
+		AddPackages(packages).
+		AddTypemap("string", "string", "").
+		SetFieldFunc(util.Camel).
+		SetIdentPolicy(util.NewIdentPolicy("type", "func", "range")).
+		Link()
+	validator.Nil(errs)
+	validator.Equal("type_", packages[0].Enums[0].Ident)
+	validator.Equal("TYPE_RANGE", packages[0].Enums[0].EnumFields[0].Ident)
+	validator.Equal("range", packages[0].Enums[0].EnumFields[0].Name)
+	validator.Equal("type_", packages[0].Structures[0].Fields[0].Ident)
+	validator.Equal("type_", packages[0].Structures[0].Fields[0].Type.Name)
+	validator.Equal("type", packages[0].Structures[0].Fields[0].Name)
+	validator.Equal("func_", packages[0].Structures[0].Fields[1].Ident)
+}
+
+func TestLinkIdentNonASCII(t *testing.T) {
+	validator := require.New(t)
+	packages := []*parser.Package{{
+		Name: "demo",
+		Structures: []*parser.Structure{{
+			Name: "demo",
+			Fields: []*parser.Field{{
+				Name: "名称",
+				Type: &parser.Type{Raw: "string"},
+			}},
+		}},
+	}}
+	_, errs := NewLinker().
+		AddPackages(packages).
+		AddTypemap("string", "string", "").
+		SetFieldFunc(util.ProtoPascal).
+		Link()
+	validator.Len(errs, 1)
+}
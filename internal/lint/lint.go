@@ -0,0 +1,147 @@
+package lint
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"sort"
+
+	"github.com/wzyjerry/windranger/internal/parser"
+	"gopkg.in/yaml.v3"
+)
+
+type Level uint32
+
+const (
+	LevelOff Level = iota
+	LevelWarning
+	LevelError
+)
+
+var levelName = [...]string{
+	LevelOff:     "off",
+	LevelWarning: "warning",
+	LevelError:   "error",
+}
+
+func (l Level) String() string {
+	return levelName[l]
+}
+
+// parseLevel 解析规则级别
+func parseLevel(s string) (Level, error) {
+	for level, name := range levelName {
+		if name == s {
+			return Level(level), nil
+		}
+	}
+	return LevelOff, fmt.Errorf("未知规则级别: %s", s)
+}
+
+// Diagnostic 检查结果
+type Diagnostic struct {
+	// Rule 规则名
+	Rule  string
+	Level Level
+	// Message 检查信息
+	Message string
+	// Pos 被检查定义的位置
+	Pos parser.Position
+}
+
+func (d *Diagnostic) String() string {
+	if d.Pos.File != "" {
+		return fmt.Sprintf("%s: %s: [%s] %s", d.Pos, d.Level, d.Rule, d.Message)
+	}
+	return fmt.Sprintf("%s: [%s] %s", d.Level, d.Rule, d.Message)
+}
+
+// HasError 是否包含错误级别的检查结果
+func HasError(diagnostics []*Diagnostic) bool {
+	for _, diagnostic := range diagnostics {
+		if diagnostic.Level == LevelError {
+			return true
+		}
+	}
+	return false
+}
+
+// Config windranger.yaml中的lint配置
+//
+//	lint:
+//	  max_depth: 5
+//	  rules:
+//	    missing-comment: off
+//	    primary-key: error
+type Config struct {
+	// Rules 规则级别覆盖，取值为off、warning或error
+	Rules map[string]string `yaml:"rules"`
+	// MaxDepth 表的最大嵌套深度
+	MaxDepth int `yaml:"max_depth"`
+}
+
+// DefaultMaxDepth 默认最大嵌套深度
+const DefaultMaxDepth = 5
+
+// LoadConfig 读取配置目录中windranger.yaml的lint配置
+func LoadConfig(root string) (*Config, error) {
+	content, err := os.ReadFile(path.Join(root, "windranger.yaml"))
+	if err != nil {
+		return nil, err
+	}
+	return ParseConfig(content)
+}
+
+// ParseConfig 解析windranger.yaml内容中的lint配置，内容为空时使用默认配置
+func ParseConfig(content []byte) (*Config, error) {
+	var cfg struct {
+		Lint Config `yaml:"lint"`
+	}
+	if err := yaml.Unmarshal(content, &cfg); err != nil {
+		return nil, err
+	}
+	return &cfg.Lint, nil
+}
+
+// Run 按配置运行所有规则，注释指令抑制的规则不会报告
+func Run(packages []*parser.Package, cfg *Config) ([]*Diagnostic, error) {
+	if cfg == nil {
+		cfg = new(Config)
+	}
+	levels := make(map[string]Level, len(rules))
+	for _, r := range rules {
+		levels[r.name] = r.level
+	}
+	names := make([]string, 0, len(cfg.Rules))
+	for name := range cfg.Rules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if _, ok := levels[name]; !ok {
+			return nil, fmt.Errorf("未知规则: %s", name)
+		}
+		level, err := parseLevel(cfg.Rules[name])
+		if err != nil {
+			return nil, err
+		}
+		levels[name] = level
+	}
+	c := newChecker(packages, cfg)
+	for _, r := range rules {
+		if c.level = levels[r.name]; c.level == LevelOff {
+			continue
+		}
+		c.rule = r.name
+		r.check(c)
+	}
+	return c.diagnostics, nil
+}
+
+// CheckDeprecated 检查未废弃字段引用已废弃类型
+func CheckDeprecated(packages []*parser.Package) []*Diagnostic {
+	c := newChecker(packages, new(Config))
+	c.rule, c.level = ruleDeprecatedReference, LevelWarning
+	checkDeprecatedReference(c)
+	return c.diagnostics
+}
@@ -0,0 +1,130 @@
+package lint
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/wzyjerry/windranger/internal/parser"
+)
+
+func messages(diagnostics []*Diagnostic) []string {
+	result := make([]string, len(diagnostics))
+	for i, diagnostic := range diagnostics {
+		result[i] = diagnostic.String()
+	}
+	return result
+}
+
+func TestCheckDeprecated(t *testing.T) {
+	validator := require.New(t)
+	packages := []*parser.Package{{
+		Name: "demo",
+		Structures: []*parser.Structure{{
+			Name:       "old_author",
+			Deprecated: &parser.Deprecation{Replacement: "author"},
+		}, {
+			Name: "demo",
+			Fields: []*parser.Field{{
+				Name: "author",
+				Type: &parser.Type{Raw: "old_author"},
+			}, {
+				Name:       "legacy",
+				Type:       &parser.Type{Raw: "old_author"},
+				Deprecated: &parser.Deprecation{},
+			}},
+		}},
+	}}
+	validator.Equal([]string{
+		"warning: [deprecated-reference] 字段demo.author引用了已废弃的类型old_author: 使用author替代",
+	}, messages(CheckDeprecated(packages)))
+}
+
+func TestRun(t *testing.T) {
+	validator := require.New(t)
+	p := parser.NewParser()
+	p.AddYaml([]byte(`version: v1
+kind: Model
+metadata:
+  name: demo
+spec:
+  # 示例
+  demo:
+    name: string # 名称
+    tag[]: string
+    authors: # 作者 windranger:ignore plural
+      Name: string # windranger:ignore missing-comment
+  # 未使用
+  unused: [a]
+`))
+	packages, errs := p.Parse()
+	validator.Nil(errs)
+	diagnostics, err := Run(packages, &Config{
+		Rules: map[string]string{
+			"missing-comment": "error",
+		},
+	})
+	validator.Nil(err)
+	validator.Equal([]string{
+		"warning: [naming] 字段authors.Name的名称不是snake形式",
+		"error: [missing-comment] 字段demo.tag缺少注释",
+		"warning: [plural] 数组demo.tag应使用复数名称",
+		"warning: [unused] 枚举unused未被使用",
+		"error: [primary-key] 表demo缺少主键",
+	}, messages(diagnostics))
+	validator.True(HasError(diagnostics))
+}
+
+func TestRunConfig(t *testing.T) {
+	validator := require.New(t)
+	packages := []*parser.Package{{
+		Name: "demo",
+		Structures: []*parser.Structure{{
+			Name:    "a",
+			Comment: "a",
+			Fields: []*parser.Field{{
+				Name:    "b",
+				Comment: "b",
+				Type:    &parser.Type{Raw: "b"},
+			}},
+		}, {
+			Name:    "b",
+			Comment: "b",
+		}, {
+			Name:    "demo",
+			Comment: "demo",
+			Fields: []*parser.Field{{
+				Name:    "id",
+				Comment: "id",
+				Type:    &parser.Type{Raw: "string", Kind: parser.KindPrimaryKey},
+			}, {
+				Name:    "a",
+				Comment: "a",
+				Type:    &parser.Type{Raw: "a"},
+			}},
+		}},
+		Collection: &parser.Collection{Name: "demo"},
+	}}
+	diagnostics, err := Run(packages, &Config{MaxDepth: 2})
+	validator.Nil(err)
+	validator.Equal([]string{
+		"warning: [max-depth] 表demo的嵌套深度3超过2",
+	}, messages(diagnostics))
+	diagnostics, err = Run(packages, &Config{MaxDepth: 2, Rules: map[string]string{"max-depth": "off"}})
+	validator.Nil(err)
+	validator.Empty(diagnostics)
+	_, err = Run(packages, &Config{Rules: map[string]string{"unknown": "off"}})
+	validator.NotNil(err)
+	_, err = Run(packages, &Config{Rules: map[string]string{"naming": "fatal"}})
+	validator.NotNil(err)
+}
+
+func TestParseConfig(t *testing.T) {
+	validator := require.New(t)
+	cfg, err := ParseConfig([]byte("version: v1\nkind: Windranger\nlint:\n  max_depth: 2\n  rules:\n    naming: off\n"))
+	validator.Nil(err)
+	validator.Equal(&Config{MaxDepth: 2, Rules: map[string]string{"naming": "off"}}, cfg)
+	// 标准输入没有配置文件，使用默认配置
+	cfg, err = ParseConfig(nil)
+	validator.Nil(err)
+	validator.Equal(&Config{}, cfg)
+}
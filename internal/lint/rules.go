@@ -0,0 +1,278 @@
+package lint
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/wzyjerry/windranger/internal/parser"
+	"github.com/wzyjerry/windranger/internal/util"
+)
+
+const (
+	ruleNaming              = "naming"
+	ruleMissingComment      = "missing-comment"
+	rulePlural              = "plural"
+	ruleUnused              = "unused"
+	rulePrimaryKey          = "primary-key"
+	ruleMaxDepth            = "max-depth"
+	ruleDeprecatedReference = "deprecated-reference"
+)
+
+// rule 检查规则
+type rule struct {
+	name string
+	// level 默认级别
+	level Level
+	check func(c *checker)
+}
+
+// rules 所有规则，按报告顺序排列
+var rules = []*rule{
+	{name: ruleNaming, level: LevelWarning, check: checkNaming},
+	{name: ruleMissingComment, level: LevelWarning, check: checkMissingComment},
+	{name: rulePlural, level: LevelWarning, check: checkPlural},
+	{name: ruleUnused, level: LevelWarning, check: checkUnused},
+	{name: rulePrimaryKey, level: LevelError, check: checkPrimaryKey},
+	{name: ruleMaxDepth, level: LevelWarning, check: checkMaxDepth},
+	{name: ruleDeprecatedReference, level: LevelWarning, check: checkDeprecatedReference},
+}
+
+// checker 规则运行上下文
+type checker struct {
+	packages    []*parser.Package
+	cfg         *Config
+	enums       map[string]*parser.Enum
+	structures  map[string]*parser.Structure
+	diagnostics []*Diagnostic
+	// 当前规则
+	rule  string
+	level Level
+}
+
+func newChecker(packages []*parser.Package, cfg *Config) *checker {
+	c := &checker{
+		packages:    packages,
+		cfg:         cfg,
+		enums:       make(map[string]*parser.Enum),
+		structures:  make(map[string]*parser.Structure),
+		diagnostics: make([]*Diagnostic, 0),
+	}
+	for _, pack := range packages {
+		for _, enum := range pack.Enums {
+			c.enums[enum.Name] = enum
+		}
+		for _, structure := range pack.Structures {
+			c.structures[structure.Name] = structure
+		}
+	}
+	return c
+}
+
+// report 报告当前规则在pos处的检查结果，suppress中包含当前规则时忽略
+func (c *checker) report(suppress []string, pos parser.Position, format string, args ...any) {
+	for _, name := range suppress {
+		if name == c.rule {
+			return
+		}
+	}
+	c.diagnostics = append(c.diagnostics, &Diagnostic{
+		Rule:    c.rule,
+		Level:   c.level,
+		Message: fmt.Sprintf(format, args...),
+		Pos:     pos,
+	})
+}
+
+// table 返回包中的表结构
+func table(pack *parser.Package) *parser.Structure {
+	if pack.Collection == nil {
+		return nil
+	}
+	for _, structure := range pack.Structures {
+		if structure.Name == pack.Name {
+			return structure
+		}
+	}
+	return nil
+}
+
+var snake = regexp.MustCompile(`^[a-z][a-z0-9]*(_[a-z0-9]+)*$`)
+
+// checkNaming 名称使用snake形式
+func checkNaming(c *checker) {
+	for _, pack := range c.packages {
+		for _, enum := range pack.Enums {
+			if !snake.MatchString(enum.Name) {
+				c.report(enum.Suppress, enum.Pos, "枚举%s的名称不是snake形式", enum.Name)
+			}
+			for _, field := range enum.EnumFields {
+				if !snake.MatchString(field.Name) {
+					c.report(field.Suppress, field.Pos, "枚举值%s.%s的名称不是snake形式", enum.Name, field.Name)
+				}
+			}
+		}
+		for _, structure := range pack.Structures {
+			if !snake.MatchString(structure.Name) {
+				c.report(structure.Suppress, structure.Pos, "结构%s的名称不是snake形式", structure.Name)
+			}
+			for _, field := range structure.Fields {
+				if !snake.MatchString(field.Name) {
+					c.report(field.Suppress, field.Pos, "字段%s.%s的名称不是snake形式", structure.Name, field.Name)
+				}
+			}
+		}
+	}
+}
+
+// checkMissingComment 结构、枚举和字段需要注释
+func checkMissingComment(c *checker) {
+	for _, pack := range c.packages {
+		for _, enum := range pack.Enums {
+			if enum.Comment == "" {
+				c.report(enum.Suppress, enum.Pos, "枚举%s缺少注释", enum.Name)
+			}
+		}
+		for _, structure := range pack.Structures {
+			if structure.Comment == "" {
+				c.report(structure.Suppress, structure.Pos, "结构%s缺少注释", structure.Name)
+			}
+			for _, field := range structure.Fields {
+				if field.Comment == "" {
+					c.report(field.Suppress, field.Pos, "字段%s.%s缺少注释", structure.Name, field.Name)
+				}
+			}
+		}
+	}
+}
+
+// checkPlural 使用单复数区分字段和数组
+func checkPlural(c *checker) {
+	for _, pack := range c.packages {
+		for _, structure := range pack.Structures {
+			for _, field := range structure.Fields {
+				array := field.Type.Kind == parser.KindArray
+				switch {
+				case array && !util.IsPlural(field.Name):
+					c.report(field.Suppress, field.Pos, "数组%s.%s应使用复数名称", structure.Name, field.Name)
+				case !array && !util.IsSingular(field.Name):
+					c.report(field.Suppress, field.Pos, "字段%s.%s应使用单数名称", structure.Name, field.Name)
+				}
+			}
+		}
+	}
+}
+
+// checkUnused 未被引用的枚举和结构
+func checkUnused(c *checker) {
+	used := make(map[string]struct{})
+	for _, pack := range c.packages {
+		if t := table(pack); t != nil {
+			used[t.Name] = struct{}{}
+		}
+		for _, structure := range pack.Structures {
+			for _, field := range structure.Fields {
+				used[field.Type.Raw] = struct{}{}
+			}
+		}
+	}
+	for _, pack := range c.packages {
+		for _, enum := range pack.Enums {
+			if _, ok := used[enum.Name]; !ok {
+				c.report(enum.Suppress, enum.Pos, "枚举%s未被使用", enum.Name)
+			}
+		}
+		for _, structure := range pack.Structures {
+			if _, ok := used[structure.Name]; !ok {
+				c.report(structure.Suppress, structure.Pos, "结构%s未被使用", structure.Name)
+			}
+		}
+	}
+}
+
+// checkPrimaryKey 每张表有且仅有一个主键
+func checkPrimaryKey(c *checker) {
+	for _, pack := range c.packages {
+		t := table(pack)
+		if t == nil {
+			continue
+		}
+		count := 0
+		for _, field := range t.Fields {
+			if field.Type.Kind == parser.KindPrimaryKey {
+				count++
+			}
+		}
+		switch {
+		case count == 0:
+			c.report(t.Suppress, t.Pos, "表%s缺少主键", t.Name)
+		case count > 1:
+			c.report(t.Suppress, t.Pos, "表%s有%d个主键", t.Name, count)
+		}
+	}
+}
+
+// depth 计算结构的嵌套深度，循环引用只计算一次
+func (c *checker) depth(structure *parser.Structure, visiting map[string]struct{}) int {
+	if _, ok := visiting[structure.Name]; ok {
+		return 0
+	}
+	visiting[structure.Name] = struct{}{}
+	defer delete(visiting, structure.Name)
+	max := 0
+	for _, field := range structure.Fields {
+		if next, ok := c.structures[field.Type.Raw]; ok {
+			if d := c.depth(next, visiting); d > max {
+				max = d
+			}
+		}
+	}
+	return max + 1
+}
+
+// checkMaxDepth 表的嵌套深度不超过配置
+func checkMaxDepth(c *checker) {
+	max := c.cfg.MaxDepth
+	if max <= 0 {
+		max = DefaultMaxDepth
+	}
+	for _, pack := range c.packages {
+		t := table(pack)
+		if t == nil {
+			continue
+		}
+		if d := c.depth(t, make(map[string]struct{})); d > max {
+			c.report(t.Suppress, t.Pos, "表%s的嵌套深度%d超过%d", t.Name, d, max)
+		}
+	}
+}
+
+// checkDeprecatedReference 未废弃字段引用已废弃类型
+func checkDeprecatedReference(c *checker) {
+	for _, pack := range c.packages {
+		for _, structure := range pack.Structures {
+			if structure.Deprecated != nil {
+				continue
+			}
+			for _, field := range structure.Fields {
+				if field.Deprecated != nil {
+					continue
+				}
+				var deprecated *parser.Deprecation
+				if enum, ok := c.enums[field.Type.Raw]; ok {
+					deprecated = enum.Deprecated
+				}
+				if s, ok := c.structures[field.Type.Raw]; ok {
+					deprecated = s.Deprecated
+				}
+				if deprecated == nil {
+					continue
+				}
+				if reason := deprecated.String(); reason != "" {
+					c.report(field.Suppress, field.Pos, "字段%s.%s引用了已废弃的类型%s: %s", structure.Name, field.Name, field.Type.Raw, reason)
+				} else {
+					c.report(field.Suppress, field.Pos, "字段%s.%s引用了已废弃的类型%s", structure.Name, field.Name, field.Type.Raw)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,244 @@
+package lsp
+
+import (
+	"sort"
+	"unicode/utf8"
+
+	"github.com/wzyjerry/windranger/internal/generator/gogo"
+	"github.com/wzyjerry/windranger/internal/parser"
+	"gopkg.in/yaml.v3"
+)
+
+// occurrence 类型名在资源文件中的一次出现
+type occurrence struct {
+	name string
+	pos  parser.Position
+	// definition 是否为定义处
+	definition bool
+}
+
+// span 返回yaml位置开始、长度为length个字符的区间
+//
+// yaml的列按字符计数，与LSP的UTF-16列在基本多文种平面内一致
+func span(pos parser.Position, length int) Range {
+	if !pos.IsValid() {
+		return Range{}
+	}
+	start := Position{
+		Line:      pos.Line - 1,
+		Character: pos.Column - 1,
+	}
+	return Range{
+		Start: start,
+		End: Position{
+			Line:      start.Line,
+			Character: start.Character + length,
+		},
+	}
+}
+
+func (o *occurrence) rng() Range {
+	return span(o.pos, utf8.RuneCountInString(o.name))
+}
+
+// definition 类型定义，enum和structure有且仅有一个非nil
+type definition struct {
+	name      string
+	pos       parser.Position
+	enum      *parser.Enum
+	structure *parser.Structure
+	// inline 字段内联定义的类型，定义位置即字段名
+	inline bool
+}
+
+// index 资源文件中的类型定义和引用
+type index struct {
+	definitions map[string]*definition
+	// occurrences 按文件分组的类型名出现位置
+	occurrences map[string][]*occurrence
+	// fields 按文件分组的字段
+	fields map[string][]*parser.Field
+}
+
+func newIndex() *index {
+	return &index{
+		definitions: make(map[string]*definition),
+		occurrences: make(map[string][]*occurrence),
+		fields:      make(map[string][]*parser.Field),
+	}
+}
+
+// parseFile 单独解析并链接一个资源文件，链接失败时保留已填充的信息
+func parseFile(file string, content []byte) ([]*parser.Package, []error) {
+	packages, errs := parser.NewParser().AddYamlFile(file, content).Parse()
+	if errs != nil {
+		return nil, errs
+	}
+	gogo.Link(packages)
+	return packages, nil
+}
+
+// add 添加一个资源文件的解析结果
+func (idx *index) add(file string, content []byte, packages []*parser.Package) {
+	seen := make(map[parser.Position]struct{})
+	occur := func(name string, pos parser.Position, definition bool) {
+		if _, ok := seen[pos]; ok || !pos.IsValid() {
+			return
+		}
+		seen[pos] = struct{}{}
+		idx.occurrences[file] = append(idx.occurrences[file], &occurrence{
+			name:       name,
+			pos:        pos,
+			definition: definition,
+		})
+	}
+	tables := make(map[string]struct{})
+	for _, pack := range packages {
+		if pack.Collection != nil {
+			tables[pack.Name] = struct{}{}
+		}
+		for _, enum := range pack.Enums {
+			idx.definitions[enum.Name] = &definition{
+				name: enum.Name,
+				pos:  enum.Pos,
+				enum: enum,
+			}
+			occur(enum.Name, enum.Pos, true)
+		}
+		for _, structure := range pack.Structures {
+			idx.definitions[structure.Name] = &definition{
+				name:      structure.Name,
+				pos:       structure.Pos,
+				structure: structure,
+			}
+			occur(structure.Name, structure.Pos, true)
+		}
+	}
+	keys := make(map[parser.Position]struct{})
+	for _, pack := range packages {
+		for _, structure := range pack.Structures {
+			for _, field := range structure.Fields {
+				idx.fields[file] = append(idx.fields[file], field)
+				keys[field.Pos] = struct{}{}
+				occur(field.Type.Raw, field.Type.Pos, false)
+			}
+		}
+	}
+	for _, pack := range packages {
+		for _, enum := range pack.Enums {
+			_, idx.definitions[enum.Name].inline = keys[enum.Pos]
+		}
+		for _, structure := range pack.Structures {
+			_, idx.definitions[structure.Name].inline = keys[structure.Pos]
+		}
+	}
+	// metadata.name引用表结构
+	if pos, name, ok := metadataName(file, content); ok {
+		if _, table := tables[name]; table {
+			occur(name, pos, false)
+		}
+	}
+	sort.SliceStable(idx.occurrences[file], func(i, j int) bool {
+		a, b := idx.occurrences[file][i].pos, idx.occurrences[file][j].pos
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+}
+
+// metadataName 返回文档metadata.name的位置和值
+func metadataName(file string, content []byte) (parser.Position, string, bool) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(content, &doc); err != nil || len(doc.Content) == 0 {
+		return parser.Position{}, "", false
+	}
+	metadata := lookup(doc.Content[0], "metadata")
+	if metadata == nil {
+		return parser.Position{}, "", false
+	}
+	name := lookup(metadata, "name")
+	if name == nil || name.Kind != yaml.ScalarNode {
+		return parser.Position{}, "", false
+	}
+	return parser.Position{
+		File:   file,
+		Line:   name.Line,
+		Column: name.Column,
+	}, name.Value, true
+}
+
+// lookup 查找字典中的值
+func lookup(node *yaml.Node, key string) *yaml.Node {
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i < len(node.Content)>>1; i++ {
+		if node.Content[i<<1].Value == key {
+			return node.Content[i<<1|1]
+		}
+	}
+	return nil
+}
+
+// at 查找文件中位置处的类型名
+func (idx *index) at(file string, pos Position) *occurrence {
+	for _, o := range idx.occurrences[file] {
+		if o.rng().contains(pos) {
+			return o
+		}
+	}
+	return nil
+}
+
+// fieldAt 查找文件中位置处的字段名
+func (idx *index) fieldAt(file string, pos Position) *parser.Field {
+	for _, field := range idx.fields[file] {
+		if span(field.Pos, utf8.RuneCountInString(field.Name)).contains(pos) {
+			return field
+		}
+	}
+	return nil
+}
+
+// references 查找类型名的所有出现位置，按文件和位置排序
+func (idx *index) references(name string, includeDefinition bool) []*occurrence {
+	files := make([]string, 0, len(idx.occurrences))
+	for file := range idx.occurrences {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	result := make([]*occurrence, 0)
+	for _, file := range files {
+		for _, o := range idx.occurrences[file] {
+			if o.name == name && (includeDefinition || !o.definition) {
+				result = append(result, o)
+			}
+		}
+	}
+	return result
+}
+
+// isBuiltin 是否为内置基本类型
+func isBuiltin(name string) bool {
+	for _, builtin := range parser.BuiltinTypes {
+		if builtin == name {
+			return true
+		}
+	}
+	return false
+}
+
+// undefined 返回引用了未定义类型的位置
+func (idx *index) undefined(file string) []*occurrence {
+	result := make([]*occurrence, 0)
+	for _, o := range idx.occurrences[file] {
+		if o.definition || isBuiltin(o.name) {
+			continue
+		}
+		if _, ok := idx.definitions[o.name]; !ok {
+			result = append(result, o)
+		}
+	}
+	return result
+}
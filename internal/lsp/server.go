@@ -0,0 +1,372 @@
+package lsp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/wzyjerry/windranger/internal/generator/gogo"
+	"github.com/wzyjerry/windranger/internal/parser"
+	"github.com/wzyjerry/windranger/internal/util"
+)
+
+// Server 基于标准输入输出的windranger语言服务
+type Server struct {
+	conn *conn
+	ws   *workspace
+	// published 已发布诊断信息的文件，用于清除过期诊断
+	published map[string]struct{}
+}
+
+// NewServer 创建语言服务，r和w通常为标准输入输出
+func NewServer(r io.Reader, w io.Writer) *Server {
+	return &Server{
+		conn:      newConn(r, w),
+		ws:        newWorkspace(""),
+		published: make(map[string]struct{}),
+	}
+}
+
+// Run 处理消息直到客户端发出exit通知或输入结束
+func (s *Server) Run() error {
+	for {
+		msg, err := s.conn.read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		var e *responseError
+		if errors.As(err, &e) {
+			// 无法解析的消息没有可用的ID，忽略
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if msg.Method == "exit" {
+			return nil
+		}
+		result, err := s.handle(msg)
+		if msg.ID == nil {
+			continue
+		}
+		if err := s.conn.reply(msg.ID, result, err); err != nil {
+			return err
+		}
+	}
+}
+
+// decode 解析请求参数
+func decode[T any](msg *message) (*T, error) {
+	params := new(T)
+	if err := json.Unmarshal(msg.Params, params); err != nil {
+		return nil, &responseError{
+			Code:    codeInvalidParams,
+			Message: err.Error(),
+		}
+	}
+	return params, nil
+}
+
+// handle 分发消息，通知的返回值被忽略
+func (s *Server) handle(msg *message) (any, error) {
+	switch msg.Method {
+	case "initialize":
+		params, err := decode[initializeParams](msg)
+		if err != nil {
+			return nil, err
+		}
+		return s.initialize(params), nil
+	case "initialized":
+		return nil, s.refresh()
+	case "shutdown":
+		return nil, nil
+	case "textDocument/didOpen":
+		params, err := decode[didOpenTextDocumentParams](msg)
+		if err != nil {
+			return nil, err
+		}
+		s.ws.overlay[uriToPath(params.TextDocument.URI)] = []byte(params.TextDocument.Text)
+		return nil, s.refresh()
+	case "textDocument/didChange":
+		params, err := decode[didChangeTextDocumentParams](msg)
+		if err != nil {
+			return nil, err
+		}
+		// 全量同步，最后一次变更即为完整内容
+		if n := len(params.ContentChanges); n != 0 {
+			s.ws.overlay[uriToPath(params.TextDocument.URI)] = []byte(params.ContentChanges[n-1].Text)
+		}
+		return nil, s.refresh()
+	case "textDocument/didClose":
+		params, err := decode[didCloseTextDocumentParams](msg)
+		if err != nil {
+			return nil, err
+		}
+		delete(s.ws.overlay, uriToPath(params.TextDocument.URI))
+		return nil, s.refresh()
+	case "textDocument/didSave":
+		return nil, s.refresh()
+	case "textDocument/completion":
+		params, err := decode[textDocumentPositionParams](msg)
+		if err != nil {
+			return nil, err
+		}
+		return s.completion(params), nil
+	case "textDocument/definition":
+		params, err := decode[textDocumentPositionParams](msg)
+		if err != nil {
+			return nil, err
+		}
+		return s.definition(params), nil
+	case "textDocument/references":
+		params, err := decode[referenceParams](msg)
+		if err != nil {
+			return nil, err
+		}
+		return s.references(params), nil
+	case "textDocument/hover":
+		params, err := decode[textDocumentPositionParams](msg)
+		if err != nil {
+			return nil, err
+		}
+		return s.hover(params), nil
+	case "textDocument/rename":
+		params, err := decode[renameParams](msg)
+		if err != nil {
+			return nil, err
+		}
+		return s.rename(params)
+	}
+	return nil, &responseError{
+		Code:    codeMethodNotFound,
+		Message: fmt.Sprintf("未知方法: %s", msg.Method),
+	}
+}
+
+// initialize 记录配置目录并声明服务能力
+func (s *Server) initialize(params *initializeParams) *initializeResult {
+	root := params.RootPath
+	if params.RootURI != "" {
+		root = uriToPath(params.RootURI)
+	}
+	s.ws = newWorkspace(root)
+	result := new(initializeResult)
+	result.ServerInfo.Name = "windranger"
+	result.Capabilities.TextDocumentSync = syncFull
+	result.Capabilities.HoverProvider = true
+	result.Capabilities.DefinitionProvider = true
+	result.Capabilities.ReferencesProvider = true
+	result.Capabilities.RenameProvider = true
+	result.Capabilities.CompletionProvider.TriggerCharacters = []string{" "}
+	return result
+}
+
+// refresh 重新分析并发布诊断信息
+func (s *Server) refresh() error {
+	s.ws.analyze()
+	files := make([]string, 0, len(s.ws.diagnostics)+len(s.published))
+	for file := range s.published {
+		files = append(files, file)
+	}
+	for file := range s.ws.diagnostics {
+		if _, ok := s.published[file]; !ok {
+			files = append(files, file)
+		}
+	}
+	sort.Strings(files)
+	for _, file := range files {
+		diagnostics := s.ws.diagnostics[file]
+		if diagnostics == nil {
+			diagnostics = make([]Diagnostic, 0)
+			delete(s.published, file)
+		} else {
+			s.published[file] = struct{}{}
+		}
+		err := s.conn.notify("textDocument/publishDiagnostics", &publishDiagnosticsParams{
+			URI:         pathToURI(file),
+			Diagnostics: diagnostics,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// completion 在值的位置补全类型名
+func (s *Server) completion(params *textDocumentPositionParams) []CompletionItem {
+	items := make([]CompletionItem, 0)
+	if !strings.Contains(s.ws.lineText(uriToPath(params.TextDocument.URI), params.Position), ":") {
+		return items
+	}
+	for _, name := range parser.BuiltinTypes {
+		items = append(items, CompletionItem{
+			Label:  name,
+			Kind:   completionKeyword,
+			Detail: util.GoType(*gogo.Typemap[name]),
+		})
+	}
+	names := make([]string, 0, len(s.ws.index.definitions))
+	for name := range s.ws.index.definitions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		def := s.ws.index.definitions[name]
+		item := CompletionItem{
+			Label: name,
+			Kind:  completionClass,
+		}
+		if def.enum != nil {
+			item.Kind = completionEnum
+			item.Detail, item.Documentation = def.enum.Ident, def.enum.Comment
+		} else {
+			item.Detail, item.Documentation = def.structure.Ident, def.structure.Comment
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+// definition 跳转到类型定义
+func (s *Server) definition(params *textDocumentPositionParams) *Location {
+	o := s.ws.index.at(uriToPath(params.TextDocument.URI), params.Position)
+	if o == nil {
+		return nil
+	}
+	def, ok := s.ws.index.definitions[o.name]
+	if !ok {
+		return nil
+	}
+	return &Location{
+		URI:   pathToURI(def.pos.File),
+		Range: span(def.pos, len([]rune(def.name))),
+	}
+}
+
+// references 查找类型名的所有引用
+func (s *Server) references(params *referenceParams) []Location {
+	locations := make([]Location, 0)
+	o := s.ws.index.at(uriToPath(params.TextDocument.URI), params.Position)
+	if o == nil {
+		return locations
+	}
+	for _, ref := range s.ws.index.references(o.name, params.Context.IncludeDeclaration) {
+		locations = append(locations, Location{
+			URI:   pathToURI(ref.pos.File),
+			Range: ref.rng(),
+		})
+	}
+	return locations
+}
+
+// goBlock 返回markdown格式的Go代码块
+func goBlock(code string) string {
+	return "```go\n" + code + "\n```"
+}
+
+// describe 拼接悬停信息的注释和废弃说明
+func describe(block string, comment string, deprecated *parser.Deprecation) string {
+	parts := []string{block}
+	if comment != "" {
+		parts = append(parts, comment)
+	}
+	if deprecated != nil {
+		parts = append(parts, strings.TrimSpace("**Deprecated:** "+deprecated.String()))
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// hoverDefinition 类型的悬停信息
+func hoverDefinition(name string, def *definition) string {
+	if def == nil {
+		if t, ok := gogo.Typemap[name]; ok {
+			return goBlock(util.GoType(*t))
+		}
+		return ""
+	}
+	if def.enum != nil {
+		values := make([]string, len(def.enum.EnumFields))
+		for i, field := range def.enum.EnumFields {
+			values[i] = field.Name
+		}
+		return describe(goBlock("type "+def.enum.Ident+" int"), def.enum.Comment, def.enum.Deprecated) +
+			"\n\n" + strings.Join(values, ", ")
+	}
+	var builder strings.Builder
+	builder.WriteString("type " + def.structure.Ident + " struct {\n")
+	for _, field := range def.structure.Fields {
+		builder.WriteString("\t" + field.Ident + " " + util.GoType(*field.Type) + "\n")
+	}
+	builder.WriteString("}")
+	return describe(goBlock(builder.String()), def.structure.Comment, def.structure.Deprecated)
+}
+
+// hover 显示类型或字段的注释及生成的Go类型
+func (s *Server) hover(params *textDocumentPositionParams) *Hover {
+	file := uriToPath(params.TextDocument.URI)
+	if o := s.ws.index.at(file, params.Position); o != nil {
+		value := hoverDefinition(o.name, s.ws.index.definitions[o.name])
+		if value == "" {
+			return nil
+		}
+		rng := o.rng()
+		return &Hover{
+			Contents: MarkupContent{
+				Kind:  "markdown",
+				Value: value,
+			},
+			Range: &rng,
+		}
+	}
+	if field := s.ws.index.fieldAt(file, params.Position); field != nil {
+		rng := span(field.Pos, len([]rune(field.Name)))
+		return &Hover{
+			Contents: MarkupContent{
+				Kind:  "markdown",
+				Value: describe(goBlock(field.Ident+" "+util.GoType(*field.Type)), field.Comment, field.Deprecated),
+			},
+			Range: &rng,
+		}
+	}
+	return nil
+}
+
+// typeName 合法的类型名，不能包含yaml分隔符和字段标记
+var typeName = regexp.MustCompile(`^[^\s:#,\[\]{}?!"']+$`)
+
+// rename 跨文件重命名类型
+func (s *Server) rename(params *renameParams) (*WorkspaceEdit, error) {
+	o := s.ws.index.at(uriToPath(params.TextDocument.URI), params.Position)
+	if o == nil {
+		return nil, fmt.Errorf("光标处没有类型名")
+	}
+	if isBuiltin(o.name) {
+		return nil, fmt.Errorf("不能重命名内置类型: %s", o.name)
+	}
+	// 内联类型的名称即字段名，重命名会改变存储的字段名
+	if def, ok := s.ws.index.definitions[o.name]; ok && def.inline {
+		return nil, fmt.Errorf("不能重命名内联定义的类型，其名称即字段名: %s", o.name)
+	}
+	if !typeName.MatchString(params.NewName) || isBuiltin(params.NewName) {
+		return nil, fmt.Errorf("无效的类型名: %s", params.NewName)
+	}
+	if _, ok := s.ws.index.definitions[params.NewName]; ok {
+		return nil, fmt.Errorf("类型已存在: %s", params.NewName)
+	}
+	edit := &WorkspaceEdit{
+		Changes: make(map[string][]TextEdit),
+	}
+	for _, ref := range s.ws.index.references(o.name, true) {
+		uri := pathToURI(ref.pos.File)
+		edit.Changes[uri] = append(edit.Changes[uri], TextEdit{
+			Range:   ref.rng(),
+			NewText: params.NewName,
+		})
+	}
+	return edit, nil
+}
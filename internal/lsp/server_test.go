@@ -0,0 +1,244 @@
+package lsp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testProfile = `version: v1
+kind: Windranger
+resources:
+  - common.yaml
+  - user.yaml
+`
+	testCommon = `version: v1
+kind: Model
+metadata:
+  name: common
+spec:
+  # 性别
+  gender:
+    - male # 男
+    - female # 女
+  # 地址
+  address:
+    city: string # 城市
+`
+	testUser = `version: v1
+kind: Model
+metadata:
+  name: user
+spec:
+  # 用户
+  user:
+    id!: objectid # 主键
+    sex: gender # 性别
+    home: address # 住址
+    work: office # 单位
+`
+)
+
+// session 按顺序发送消息并收集服务端输出
+func session(t *testing.T, messages ...any) map[string][]json.RawMessage {
+	input := bytes.NewBuffer(nil)
+	for _, msg := range messages {
+		body, err := json.Marshal(msg)
+		require.Nil(t, err)
+		fmt.Fprintf(input, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	}
+	output := bytes.NewBuffer(nil)
+	require.Nil(t, NewServer(input, output).Run())
+	result := make(map[string][]json.RawMessage)
+	c := newConn(output, nil)
+	for {
+		var msg struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+			Params json.RawMessage `json:"params"`
+			Result json.RawMessage `json:"result"`
+			Error  *responseError  `json:"error"`
+		}
+		header, err := c.reader.ReadMIMEHeader()
+		if err != nil {
+			break
+		}
+		var length int
+		fmt.Sscan(header.Get("Content-Length"), &length)
+		body := make([]byte, length)
+		_, err = io.ReadFull(c.reader.R, body)
+		require.Nil(t, err)
+		require.Nil(t, json.Unmarshal(body, &msg))
+		switch {
+		case msg.Method != "":
+			result[msg.Method] = append(result[msg.Method], msg.Params)
+		case msg.Error != nil:
+			result[string(msg.ID)] = append(result[string(msg.ID)], json.RawMessage(`"`+msg.Error.Message+`"`))
+		default:
+			result[string(msg.ID)] = append(result[string(msg.ID)], msg.Result)
+		}
+	}
+	return result
+}
+
+func request(id int, method string, params any) map[string]any {
+	return map[string]any{"jsonrpc": "2.0", "id": id, "method": method, "params": params}
+}
+
+func notify(method string, params any) map[string]any {
+	return map[string]any{"jsonrpc": "2.0", "method": method, "params": params}
+}
+
+func at(uri string, line int, character int) map[string]any {
+	return map[string]any{
+		"textDocument": map[string]any{"uri": uri},
+		"position":     map[string]any{"line": line, "character": character},
+	}
+}
+
+func TestServer(t *testing.T) {
+	validator := require.New(t)
+	root := t.TempDir()
+	validator.Nil(os.WriteFile(path.Join(root, "windranger.yaml"), []byte(testProfile), os.ModePerm))
+	validator.Nil(os.WriteFile(path.Join(root, "common.yaml"), []byte(testCommon), os.ModePerm))
+	validator.Nil(os.WriteFile(path.Join(root, "user.yaml"), []byte(testUser), os.ModePerm))
+	common, user := pathToURI(path.Join(root, "common.yaml")), pathToURI(path.Join(root, "user.yaml"))
+	references := at(user, 8, 10)
+	references["context"] = map[string]any{"includeDeclaration": true}
+	rename := at(user, 8, 10)
+	rename["newName"] = "sex_type"
+	result := session(t,
+		request(1, "initialize", map[string]any{"rootUri": pathToURI(root)}),
+		notify("initialized", map[string]any{}),
+		request(2, "textDocument/definition", at(user, 8, 10)),
+		request(3, "textDocument/references", references),
+		request(4, "textDocument/hover", at(user, 9, 5)),
+		request(5, "textDocument/completion", at(user, 10, 10)),
+		request(6, "textDocument/rename", rename),
+		request(7, "textDocument/rename", at(user, 7, 9)),
+		request(8, "shutdown", nil),
+		notify("exit", nil),
+	)
+
+	// 诊断
+	var diagnostics []publishDiagnosticsParams
+	for _, raw := range result["textDocument/publishDiagnostics"] {
+		var params publishDiagnosticsParams
+		validator.Nil(json.Unmarshal(raw, &params))
+		diagnostics = append(diagnostics, params)
+	}
+	validator.Len(diagnostics, 1)
+	validator.Equal(user, diagnostics[0].URI)
+	validator.Equal([]Diagnostic{{
+		Range:    Range{Start: Position{Line: 10, Character: 10}, End: Position{Line: 10, Character: 16}},
+		Severity: severityError,
+		Source:   "windranger",
+		Message:  "未定义的类型: office",
+	}}, diagnostics[0].Diagnostics)
+
+	// 跳转定义
+	var location Location
+	validator.Nil(json.Unmarshal(result["2"][0], &location))
+	validator.Equal(Location{
+		URI:   common,
+		Range: Range{Start: Position{Line: 6, Character: 2}, End: Position{Line: 6, Character: 8}},
+	}, location)
+
+	// 查找引用
+	var locations []Location
+	validator.Nil(json.Unmarshal(result["3"][0], &locations))
+	validator.Equal([]Location{location, {
+		URI:   user,
+		Range: Range{Start: Position{Line: 8, Character: 9}, End: Position{Line: 8, Character: 15}},
+	}}, locations)
+
+	// 悬停
+	var hover Hover
+	validator.Nil(json.Unmarshal(result["4"][0], &hover))
+	validator.Equal("```go\nHome Address\n```\n\n住址", hover.Contents.Value)
+
+	// 补全
+	var items []CompletionItem
+	validator.Nil(json.Unmarshal(result["5"][0], &items))
+	labels := make([]string, len(items))
+	for i, item := range items {
+		labels[i] = item.Label
+	}
+	validator.Equal([]string{"int", "float", "bool", "string", "datetime", "objectid", "address", "gender", "user"}, labels)
+
+	// 重命名
+	var edit WorkspaceEdit
+	validator.Nil(json.Unmarshal(result["6"][0], &edit))
+	validator.Equal(map[string][]TextEdit{
+		common: {{Range: location.Range, NewText: "sex_type"}},
+		user:   {{Range: locations[1].Range, NewText: "sex_type"}},
+	}, edit.Changes)
+	validator.Equal(`"不能重命名内置类型: objectid"`, string(result["7"][0]))
+	validator.Equal("null", string(result["8"][0]))
+}
+
+func TestServerOverlay(t *testing.T) {
+	validator := require.New(t)
+	file := path.Join(t.TempDir(), "user.yaml")
+	uri := pathToURI(file)
+	result := session(t,
+		request(1, "initialize", map[string]any{}),
+		notify("textDocument/didOpen", map[string]any{
+			"textDocument": map[string]any{"uri": uri, "languageId": "yaml", "version": 1, "text": testUser},
+		}),
+		notify("textDocument/didChange", map[string]any{
+			"textDocument":   map[string]any{"uri": uri, "version": 2},
+			"contentChanges": []any{map[string]any{"text": testUser + "    work: office\n"}},
+		}),
+		request(2, "unknown/method", nil),
+		notify("exit", nil),
+	)
+	var diagnostics []publishDiagnosticsParams
+	for _, raw := range result["textDocument/publishDiagnostics"] {
+		var params publishDiagnosticsParams
+		validator.Nil(json.Unmarshal(raw, &params))
+		diagnostics = append(diagnostics, params)
+	}
+	validator.Len(diagnostics, 2)
+	messages := make([]string, 0)
+	for _, d := range diagnostics[1].Diagnostics {
+		messages = append(messages, d.Message)
+	}
+	validator.Contains(messages, "重复的字段名: work")
+	validator.Equal(`"未知方法: unknown/method"`, string(result["2"][0]))
+}
+
+func TestServerRenameInline(t *testing.T) {
+	validator := require.New(t)
+	file := path.Join(t.TempDir(), "user.yaml")
+	uri := pathToURI(file)
+	rename := at(uri, 8, 5)
+	rename["newName"] = "place"
+	result := session(t,
+		request(1, "initialize", map[string]any{}),
+		notify("textDocument/didOpen", map[string]any{
+			"textDocument": map[string]any{"uri": uri, "languageId": "yaml", "version": 1, "text": `version: v1
+kind: Model
+metadata:
+  name: user
+spec:
+  # 用户
+  user:
+    id!: objectid # 主键
+    home: # 住址
+      city: string # 城市
+`},
+		}),
+		request(2, "textDocument/rename", rename),
+		notify("exit", nil),
+	)
+	// 内联类型的名称即字段名，重命名会改变存储的字段名
+	validator.Equal(`"不能重命名内联定义的类型，其名称即字段名: home"`, string(result["2"][0]))
+}
@@ -0,0 +1,249 @@
+package lsp
+
+import (
+	"errors"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/wzyjerry/windranger/internal/generator/gogo"
+	"github.com/wzyjerry/windranger/internal/lint"
+	"github.com/wzyjerry/windranger/internal/parser"
+)
+
+// profile 配置文件名
+const profile = "windranger.yaml"
+
+// uriToPath 将file URI转换为路径
+func uriToPath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return uri
+	}
+	return u.Path
+}
+
+// pathToURI 将路径转换为file URI
+func pathToURI(name string) string {
+	return (&url.URL{
+		Scheme: "file",
+		Path:   name,
+	}).String()
+}
+
+// workspace 配置目录及编辑器中打开的文档
+type workspace struct {
+	// root 配置目录，为空时仅分析打开的文档
+	root string
+	// overlay 打开文档的最新内容，优先于磁盘内容
+	overlay map[string][]byte
+	// last 资源文件最近一次解析成功的结果，解析失败时沿用
+	last map[string][]*parser.Package
+
+	// 最近一次分析结果
+	files       []string
+	contents    map[string][]byte
+	index       *index
+	diagnostics map[string][]Diagnostic
+}
+
+func newWorkspace(root string) *workspace {
+	return &workspace{
+		root:        root,
+		overlay:     make(map[string][]byte),
+		last:        make(map[string][]*parser.Package),
+		contents:    make(map[string][]byte),
+		index:       newIndex(),
+		diagnostics: make(map[string][]Diagnostic),
+	}
+}
+
+// profilePath 配置文件路径
+func (w *workspace) profilePath() string {
+	return path.Join(w.root, profile)
+}
+
+// resources 返回资源文件，无配置文件时使用打开的模型文档
+func (w *workspace) resources() ([]string, error) {
+	if w.root != "" {
+		resources, err := parser.Resources(w.root)
+		if err == nil || !errors.Is(err, os.ErrNotExist) {
+			return resources, err
+		}
+	}
+	files := make([]string, 0, len(w.overlay))
+	for file := range w.overlay {
+		if path.Base(file) != profile {
+			files = append(files, file)
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// read 读取文档内容
+func (w *workspace) read(file string) ([]byte, error) {
+	if content, ok := w.overlay[file]; ok {
+		return content, nil
+	}
+	return os.ReadFile(file)
+}
+
+// report 记录文件中的诊断信息
+func (w *workspace) report(file string, diagnostic Diagnostic) {
+	w.diagnostics[file] = append(w.diagnostics[file], diagnostic)
+}
+
+// errorFile 无法定位的错误所属的文件
+func (w *workspace) errorFile() string {
+	if w.root != "" {
+		return w.profilePath()
+	}
+	if len(w.files) != 0 {
+		return w.files[0]
+	}
+	return ""
+}
+
+// tokenLength 返回位置处的标记长度，直到空白或yaml分隔符
+func (w *workspace) tokenLength(pos parser.Position) int {
+	if !pos.IsValid() {
+		return 0
+	}
+	lines := strings.Split(string(w.contents[pos.File]), "\n")
+	if pos.Line > len(lines) {
+		return 0
+	}
+	line := []rune(lines[pos.Line-1])
+	length := 0
+	for i := pos.Column - 1; i >= 0 && i < len(line); i++ {
+		if unicode.IsSpace(line[i]) || strings.ContainsRune(":,[]{}#", line[i]) {
+			break
+		}
+		length++
+	}
+	return length
+}
+
+// reportError 记录解析或链接错误
+func (w *workspace) reportError(err error) {
+	var e *parser.Error
+	pos := parser.Position{
+		File: w.errorFile(),
+	}
+	message := err.Error()
+	if errors.As(err, &e) {
+		pos, message = e.Pos, e.Err.Error()
+	}
+	if pos.File == "" {
+		return
+	}
+	w.report(pos.File, Diagnostic{
+		Range:    span(pos, w.tokenLength(pos)),
+		Severity: severityError,
+		Source:   "windranger",
+		Message:  message,
+	})
+}
+
+// analyze 重新解析所有资源文件，更新索引和诊断信息
+func (w *workspace) analyze() {
+	w.contents = make(map[string][]byte)
+	w.diagnostics = make(map[string][]Diagnostic)
+	files, err := w.resources()
+	if err != nil {
+		w.files = nil
+		w.reportError(err)
+		return
+	}
+	w.files = files
+	p := parser.NewParser()
+	for _, file := range files {
+		content, err := w.read(file)
+		if err != nil {
+			w.reportError(err)
+			continue
+		}
+		w.contents[file] = content
+		p.AddYamlFile(file, content)
+	}
+	// 索引逐文件构建，单个文件出错不影响其他文件的跳转和补全
+	w.index = newIndex()
+	for _, file := range files {
+		content, ok := w.contents[file]
+		if !ok {
+			continue
+		}
+		if packages, errs := parseFile(file, content); errs == nil {
+			w.last[file] = packages
+		}
+		w.index.add(file, content, w.last[file])
+	}
+	for _, file := range files {
+		for _, o := range w.index.undefined(file) {
+			w.report(file, Diagnostic{
+				Range:    o.rng(),
+				Severity: severityError,
+				Source:   "windranger",
+				Message:  "未定义的类型: " + o.name,
+			})
+		}
+	}
+	packages, errs := p.Parse()
+	if errs == nil {
+		packages, errs = gogo.Link(packages)
+	}
+	for _, err := range errs {
+		w.reportError(err)
+	}
+	if errs != nil {
+		return
+	}
+	var cfg *lint.Config
+	if w.root != "" {
+		// 配置文件已在解析资源时读取，读取失败即无lint配置
+		cfg, _ = lint.LoadConfig(w.root)
+	}
+	diagnostics, err := lint.Run(packages, cfg)
+	if err != nil {
+		w.reportError(err)
+		return
+	}
+	for _, d := range diagnostics {
+		if d.Pos.File == "" {
+			continue
+		}
+		severity := severityWarning
+		if d.Level == lint.LevelError {
+			severity = severityError
+		}
+		w.report(d.Pos.File, Diagnostic{
+			Range:    span(d.Pos, w.tokenLength(d.Pos)),
+			Severity: severity,
+			Code:     d.Rule,
+			Source:   "windranger-lint",
+			Message:  d.Message,
+		})
+	}
+}
+
+// lineText 返回文档中某行光标前的文本
+func (w *workspace) lineText(file string, pos Position) string {
+	content, err := w.read(file)
+	if err != nil {
+		return ""
+	}
+	lines := strings.Split(string(content), "\n")
+	if pos.Line >= len(lines) {
+		return ""
+	}
+	line := lines[pos.Line]
+	if pos.Character < utf8.RuneCountInString(line) {
+		line = string([]rune(line)[:pos.Character])
+	}
+	return line
+}
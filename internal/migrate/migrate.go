@@ -0,0 +1,369 @@
+package migrate
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/wzyjerry/windranger/internal/diff"
+	"github.com/wzyjerry/windranger/internal/parser"
+)
+
+// Collection 迁移记录集合
+const Collection = "windranger_migrations"
+
+// Step 一次updateMany，过滤条件和更新均为扩展JSON
+type Step struct {
+	Comment string
+	// Database 数据库名，为空时使用默认数据库
+	Database   string
+	Collection string
+	Filter     string
+	// Update 更新文档或聚合管道
+	Update string
+}
+
+// Plan 迁移计划
+type Plan struct {
+	ID   string
+	Up   []*Step
+	Down []*Step
+	// Manual 无法自动迁移的变更
+	Manual []string
+}
+
+// planner 迁移计划生成器
+type planner struct {
+	plan     *Plan
+	enums    map[string]*parser.Enum
+	oldLocs  map[string][]*location
+	newLocs  map[string][]*location
+	newModel []*parser.Package
+	// converted 已在枚举与字符串之间转换的字段，存储的已是新的序号，不再重新映射
+	converted map[string]struct{}
+}
+
+// extJSON 序列化扩展JSON
+func extJSON(v any) string {
+	content, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return string(content)
+}
+
+// enumValue go后端存储的枚举值
+func enumValue(i int) map[string]string {
+	return map[string]string{"$numberInt": strconv.Itoa(i)}
+}
+
+// literal 将yaml默认值转换为扩展JSON值
+func (p *planner) literal(field *parser.Field) (any, error) {
+	raw := *field.Default
+	switch field.Type.Raw {
+	case "int":
+		if _, err := strconv.ParseInt(raw, 10, 64); err != nil {
+			return nil, fmt.Errorf("默认值不是整数: %s", raw)
+		}
+		return map[string]string{"$numberLong": raw}, nil
+	case "float":
+		if _, err := strconv.ParseFloat(raw, 64); err != nil {
+			return nil, fmt.Errorf("默认值不是浮点数: %s", raw)
+		}
+		return map[string]string{"$numberDouble": raw}, nil
+	case "bool":
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, fmt.Errorf("默认值不是布尔值: %s", raw)
+		}
+		return b, nil
+	case "string":
+		return raw, nil
+	case "datetime":
+		return map[string]string{"$date": raw}, nil
+	case "objectid":
+		return map[string]string{"$oid": raw}, nil
+	}
+	if enum, ok := p.enums[field.Type.Raw]; ok {
+		for i, enumField := range enum.EnumFields {
+			if enumField.Name == raw {
+				return enumValue(i), nil
+			}
+		}
+		return nil, fmt.Errorf("默认值不是枚举%s的值: %s", enum.Name, raw)
+	}
+	return nil, fmt.Errorf("类型%s不支持默认值", field.Type.Raw)
+}
+
+// each 遍历结构所在的每个集合位置，数组中的位置记为手动迁移
+func (p *planner) each(locs map[string][]*location, path string, f func(loc *location)) {
+	structure, _, _ := strings.Cut(path, ".")
+	for _, loc := range locs[structure] {
+		if loc.InArray {
+			p.plan.Manual = append(p.plan.Manual, fmt.Sprintf("%s: 数组中的字段需要手动迁移(%s.%s)", path, loc.Collection.Name, strings.TrimSuffix(loc.Prefix, ".")))
+			continue
+		}
+		f(loc)
+	}
+}
+
+// step 追加一对up/down操作
+func (p *planner) step(loc *location, comment string, filter any, up any, downFilter any, down any) {
+	p.plan.Up = append(p.plan.Up, &Step{
+		Comment:    comment,
+		Database:   loc.Collection.Database,
+		Collection: loc.Collection.Name,
+		Filter:     extJSON(filter),
+		Update:     extJSON(up),
+	})
+	if down == nil {
+		return
+	}
+	// 回滚按相反顺序执行
+	p.plan.Down = append([]*Step{{
+		Comment:    comment,
+		Database:   loc.Collection.Database,
+		Collection: loc.Collection.Name,
+		Filter:     extJSON(downFilter),
+		Update:     extJSON(down),
+	}}, p.plan.Down...)
+}
+
+// rename 重命名字段
+func (p *planner) rename(change *diff.Change) {
+	p.each(p.newLocs, change.Path, func(loc *location) {
+		from := loc.Prefix + storageKey(change.Old)
+		to := loc.Prefix + storageKey(change.New)
+		p.step(loc, fmt.Sprintf("重命名%s为%s", from, to),
+			map[string]any{from: map[string]any{"$exists": true}},
+			map[string]any{"$rename": map[string]string{from: to}},
+			map[string]any{to: map[string]any{"$exists": true}},
+			map[string]any{"$rename": map[string]string{to: from}},
+		)
+	})
+}
+
+// remove 删除字段，回滚无法恢复数据
+func (p *planner) remove(change *diff.Change) {
+	p.each(p.oldLocs, change.Path, func(loc *location) {
+		key := loc.Prefix + storageKey(change.Old)
+		p.step(loc, fmt.Sprintf("删除%s", key),
+			map[string]any{key: map[string]any{"$exists": true}},
+			map[string]any{"$unset": map[string]string{key: ""}},
+			nil, nil,
+		)
+		p.plan.Manual = append(p.plan.Manual, fmt.Sprintf("%s: 回滚无法恢复已删除的数据", change.Path))
+	})
+}
+
+// fill 为缺失或为null的字段设置默认值，可空的上级子文档不存在时跳过
+func (p *planner) fill(change *diff.Change) {
+	field := change.New
+	if field.Default == nil {
+		p.plan.Manual = append(p.plan.Manual, fmt.Sprintf("%s: 必填字段缺少默认值", change.Path))
+		return
+	}
+	value, err := p.literal(field)
+	if err != nil {
+		p.plan.Manual = append(p.plan.Manual, fmt.Sprintf("%s: %s", change.Path, err))
+		return
+	}
+	p.each(p.newLocs, change.Path, func(loc *location) {
+		key := loc.Prefix + storageKey(field)
+		filter := map[string]any{key: nil}
+		// 只在可空的上级子文档存在时设置，避免在null上创建字段或创建缺少其他必填字段的子文档
+		for _, parent := range loc.Optional {
+			filter[parent] = map[string]any{"$type": "object"}
+		}
+		if change.Kind == diff.ChangeAdded {
+			p.step(loc, fmt.Sprintf("设置%s的默认值", key),
+				filter,
+				map[string]any{"$set": map[string]any{key: value}},
+				map[string]any{key: map[string]any{"$exists": true}},
+				map[string]any{"$unset": map[string]string{key: ""}},
+			)
+			return
+		}
+		// 可空变更为必填，回滚无需处理
+		p.step(loc, fmt.Sprintf("设置%s的默认值", key),
+			filter,
+			map[string]any{"$set": map[string]any{key: value}},
+			nil, nil,
+		)
+	})
+}
+
+// mapping 枚举值映射，from按顺序作为$switch的分支
+type mapping struct {
+	from any
+	to   any
+}
+
+// remapPipeline 生成按映射表更新字段的聚合管道
+func remapPipeline(key string, mappings []mapping) any {
+	branches := make([]any, 0, len(mappings))
+	for _, m := range mappings {
+		branches = append(branches, map[string]any{
+			"case": map[string]any{"$eq": []any{"$" + key, m.from}},
+			"then": m.to,
+		})
+	}
+	return []any{map[string]any{
+		"$set": map[string]any{
+			key: map[string]any{
+				"$switch": map[string]any{
+					"branches": branches,
+					"default":  "$" + key,
+				},
+			},
+		},
+	}}
+}
+
+// remapStep 追加按映射表更新字段的up/down操作，down为up的逆映射
+func (p *planner) remapStep(loc *location, key string, up []mapping) {
+	upValues, downValues := make([]any, 0, len(up)), make([]any, 0, len(up))
+	down := make([]mapping, 0, len(up))
+	for _, m := range up {
+		upValues = append(upValues, m.from)
+		downValues = append(downValues, m.to)
+		down = append(down, mapping{from: m.to, to: m.from})
+	}
+	p.step(loc, fmt.Sprintf("重新映射枚举%s的值", key),
+		map[string]any{key: map[string]any{"$in": upValues}},
+		remapPipeline(key, up),
+		map[string]any{key: map[string]any{"$in": downValues}},
+		remapPipeline(key, down),
+	)
+}
+
+// remap 按名称重新映射序号变化的枚举值
+func (p *planner) remap(old *parser.Enum, new *parser.Enum) {
+	positions := make(map[string]int, len(new.EnumFields))
+	for i, field := range new.EnumFields {
+		positions[field.Name] = i
+	}
+	up := make([]mapping, 0)
+	for i, field := range old.EnumFields {
+		if j, ok := positions[field.Name]; ok && i != j {
+			up = append(up, mapping{from: enumValue(i), to: enumValue(j)})
+		}
+	}
+	for _, field := range old.EnumFields {
+		if _, ok := positions[field.Name]; !ok {
+			p.plan.Manual = append(p.plan.Manual, fmt.Sprintf("%s.%s: 已存储的枚举值需要手动处理", old.Name, field.Name))
+		}
+	}
+	if len(up) == 0 {
+		return
+	}
+	for _, pack := range p.newModel {
+		for _, structure := range pack.Structures {
+			for _, field := range structure.Fields {
+				if field.Type.Raw != new.Name {
+					continue
+				}
+				path := structure.Name + "." + field.Name
+				if _, ok := p.converted[path]; ok {
+					continue
+				}
+				if field.Type.Kind == parser.KindArray {
+					p.plan.Manual = append(p.plan.Manual, fmt.Sprintf("%s: 枚举数组需要手动迁移", path))
+					continue
+				}
+				p.each(p.newLocs, path, func(loc *location) {
+					p.remapStep(loc, loc.Prefix+storageKey(field), up)
+				})
+			}
+		}
+	}
+}
+
+// convert 枚举与字符串互相转换，枚举按序号存储，字符串为枚举值的名称，返回是否已处理
+func (p *planner) convert(change *diff.Change, oldEnums map[string]*parser.Enum) bool {
+	var up []mapping
+	switch {
+	case oldEnums[change.Old.Type.Raw] != nil && change.New.Type.Raw == "string":
+		for i, field := range oldEnums[change.Old.Type.Raw].EnumFields {
+			up = append(up, mapping{from: enumValue(i), to: field.Name})
+		}
+	case change.Old.Type.Raw == "string" && p.enums[change.New.Type.Raw] != nil:
+		for i, field := range p.enums[change.New.Type.Raw].EnumFields {
+			up = append(up, mapping{from: field.Name, to: enumValue(i)})
+		}
+		p.plan.Manual = append(p.plan.Manual, fmt.Sprintf("%s: 不是枚举值名称的字符串需要手动处理", change.Path))
+	default:
+		return false
+	}
+	p.converted[change.Path] = struct{}{}
+	if change.Old.Type.Kind == parser.KindArray || change.New.Type.Kind == parser.KindArray {
+		p.plan.Manual = append(p.plan.Manual, fmt.Sprintf("%s: 枚举数组需要手动迁移", change.Path))
+		return true
+	}
+	if len(up) == 0 {
+		return true
+	}
+	p.each(p.newLocs, change.Path, func(loc *location) {
+		p.remapStep(loc, loc.Prefix+storageKey(change.New), up)
+	})
+	return true
+}
+
+// Generate 根据两个版本的模型生成迁移计划
+//
+// 迁移记录写入Collection，up/down通过记录保证只执行一次
+func Generate(id string, old []*parser.Package, new []*parser.Package) *Plan {
+	p := &planner{
+		plan: &Plan{
+			ID:     id,
+			Up:     make([]*Step, 0),
+			Down:   make([]*Step, 0),
+			Manual: make([]string, 0),
+		},
+		enums:     make(map[string]*parser.Enum),
+		oldLocs:   locate(old),
+		newLocs:   locate(new),
+		newModel:  new,
+		converted: make(map[string]struct{}),
+	}
+	oldEnums := make(map[string]*parser.Enum)
+	for _, pack := range old {
+		for _, enum := range pack.Enums {
+			oldEnums[enum.Name] = enum
+		}
+	}
+	for _, pack := range new {
+		for _, enum := range pack.Enums {
+			p.enums[enum.Name] = enum
+		}
+	}
+	for _, change := range diff.Compare(old, new) {
+		switch {
+		case change.Kind == diff.ChangeRenamed:
+			p.rename(change)
+		case change.Kind == diff.ChangeRemoved && change.Old != nil:
+			p.remove(change)
+		case change.Kind == diff.ChangeAdded && change.New != nil && change.Breaking,
+			change.Kind == diff.ChangeAdded && change.New != nil && change.New.Default != nil:
+			p.fill(change)
+		case change.Kind == diff.ChangeKindChanged && change.Old.Type.Kind == parser.KindOptional && change.New.Type.Kind == parser.KindNormal:
+			p.fill(change)
+		case change.Kind == diff.ChangeType && p.convert(change, oldEnums):
+		case change.Breaking && change.Kind != diff.ChangeEnumValue:
+			p.plan.Manual = append(p.plan.Manual, change.String())
+		}
+	}
+	names := make([]string, 0, len(p.enums))
+	for name := range p.enums {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if old, ok := oldEnums[name]; ok {
+			p.remap(old, p.enums[name])
+		}
+	}
+	return p.plan
+}
@@ -0,0 +1,167 @@
+package migrate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/wzyjerry/windranger/internal/parser"
+)
+
+func model(fields []*parser.Field, values ...string) []*parser.Package {
+	enumFields := make([]*parser.EnumField, len(values))
+	for i, value := range values {
+		enumFields[i] = &parser.EnumField{Name: value}
+	}
+	return []*parser.Package{{
+		Name:  "demo",
+		Enums: []*parser.Enum{{Name: "state", EnumFields: enumFields}},
+		Structures: []*parser.Structure{{
+			Name: "demo",
+			Fields: append([]*parser.Field{{
+				Name: "state",
+				Type: &parser.Type{Raw: "state"},
+			}, {
+				Name: "meta",
+				Type: &parser.Type{Raw: "meta"},
+			}}, fields...),
+		}, {
+			Name: "meta",
+			Fields: []*parser.Field{{
+				Name:    "title",
+				Comment: "标题",
+				Type:    &parser.Type{Raw: "string"},
+			}},
+		}},
+		Collection: &parser.Collection{Name: "demos"},
+	}}
+}
+
+func TestGenerate(t *testing.T) {
+	validator := require.New(t)
+	def := "10"
+	old := model([]*parser.Field{{
+		Name: "legacy",
+		Type: &parser.Type{Raw: "string"},
+	}}, "on", "off")
+	new := model([]*parser.Field{{
+		Name:    "score",
+		Type:    &parser.Type{Raw: "int"},
+		Default: &def,
+	}}, "unset", "on", "off")
+	new[0].Structures[1].Fields[0].Name = "heading"
+	plan := Generate("1_test", old, new)
+	validator.Equal("1_test", plan.ID)
+	validator.Equal([]*Step{{
+		Comment:    "删除legacy",
+		Collection: "demos",
+		Filter:     `{"legacy":{"$exists":true}}`,
+		Update:     `{"$unset":{"legacy":""}}`,
+	}, {
+		Comment:    "设置score的默认值",
+		Collection: "demos",
+		Filter:     `{"score":null}`,
+		Update:     `{"$set":{"score":{"$numberLong":"10"}}}`,
+	}, {
+		Comment:    "重命名meta.title为meta.heading",
+		Collection: "demos",
+		Filter:     `{"meta.title":{"$exists":true}}`,
+		Update:     `{"$rename":{"meta.title":"meta.heading"}}`,
+	}, {
+		Comment:    "重新映射枚举state的值",
+		Collection: "demos",
+		Filter:     `{"state":{"$in":[{"$numberInt":"0"},{"$numberInt":"1"}]}}`,
+		Update:     `[{"$set":{"state":{"$switch":{"branches":[{"case":{"$eq":["$state",{"$numberInt":"0"}]},"then":{"$numberInt":"1"}},{"case":{"$eq":["$state",{"$numberInt":"1"}]},"then":{"$numberInt":"2"}}],"default":"$state"}}}}]`,
+	}}, plan.Up)
+	validator.Len(plan.Down, 3)
+	validator.Equal("重新映射枚举state的值", plan.Down[0].Comment)
+	validator.Equal(`{"$rename":{"meta.heading":"meta.title"}}`, plan.Down[1].Update)
+	validator.Equal([]string{"demo.legacy: 回滚无法恢复已删除的数据"}, plan.Manual)
+}
+
+func TestGenerateManual(t *testing.T) {
+	validator := require.New(t)
+	old := model(nil, "on", "off")
+	new := model([]*parser.Field{{
+		Name: "score",
+		Type: &parser.Type{Raw: "int"},
+	}}, "on")
+	new[0].Structures[0].Fields[1].Type.Kind = parser.KindArray
+	plan := Generate("2_test", old, new)
+	validator.Empty(plan.Up)
+	validator.Equal([]string{
+		"[breaking] kind demo.meta: 字段属性Normal变更为Array",
+		"demo.score: 必填字段缺少默认值",
+		"state.off: 已存储的枚举值需要手动处理",
+	}, plan.Manual)
+}
+
+func TestGenerateEnumString(t *testing.T) {
+	validator := require.New(t)
+	old := model(nil, "on", "off")
+	new := model(nil, "on", "off")
+	new[0].Structures[0].Fields[0].Type = &parser.Type{Raw: "string"}
+	plan := Generate("3_test", old, new)
+	validator.Equal([]*Step{{
+		Comment:    "重新映射枚举state的值",
+		Collection: "demos",
+		Filter:     `{"state":{"$in":[{"$numberInt":"0"},{"$numberInt":"1"}]}}`,
+		Update:     `[{"$set":{"state":{"$switch":{"branches":[{"case":{"$eq":["$state",{"$numberInt":"0"}]},"then":"on"},{"case":{"$eq":["$state",{"$numberInt":"1"}]},"then":"off"}],"default":"$state"}}}}]`,
+	}}, plan.Up)
+	validator.Equal([]*Step{{
+		Comment:    "重新映射枚举state的值",
+		Collection: "demos",
+		Filter:     `{"state":{"$in":["on","off"]}}`,
+		Update:     `[{"$set":{"state":{"$switch":{"branches":[{"case":{"$eq":["$state","on"]},"then":{"$numberInt":"0"}},{"case":{"$eq":["$state","off"]},"then":{"$numberInt":"1"}}],"default":"$state"}}}}]`,
+	}}, plan.Down)
+	validator.Empty(plan.Manual)
+
+	// 字符串变更为枚举
+	plan = Generate("4_test", new, old)
+	validator.Len(plan.Up, 1)
+	validator.Equal(`{"state":{"$in":["on","off"]}}`, plan.Up[0].Filter)
+	validator.Len(plan.Down, 1)
+	validator.Equal(`{"state":{"$in":[{"$numberInt":"0"},{"$numberInt":"1"}]}}`, plan.Down[0].Filter)
+	validator.Equal([]string{"demo.state: 不是枚举值名称的字符串需要手动处理"}, plan.Manual)
+}
+
+func TestGenerateOptionalParent(t *testing.T) {
+	validator := require.New(t)
+	def := "untitled"
+	old := model(nil, "on")
+	new := model(nil, "on")
+	for _, m := range [][]*parser.Package{old, new} {
+		m[0].Structures[0].Fields[1].Type.Kind = parser.KindOptional
+	}
+	new[0].Structures[1].Fields = append(new[0].Structures[1].Fields, &parser.Field{
+		Name:    "subtitle",
+		Type:    &parser.Type{Raw: "string"},
+		Default: &def,
+	})
+	plan := Generate("5_test", old, new)
+	// 可空的子文档不存在时不创建
+	validator.Equal([]*Step{{
+		Comment:    "设置meta.subtitle的默认值",
+		Collection: "demos",
+		Filter:     `{"meta":{"$type":"object"},"meta.subtitle":null}`,
+		Update:     `{"$set":{"meta.subtitle":"untitled"}}`,
+	}}, plan.Up)
+	validator.Equal(`{"meta.subtitle":{"$exists":true}}`, plan.Down[0].Filter)
+	validator.Empty(plan.Manual)
+}
+
+func TestGenerateEnumStringReordered(t *testing.T) {
+	validator := require.New(t)
+	old := model(nil, "on", "off")
+	old[0].Structures[0].Fields[0].Type = &parser.Type{Raw: "string"}
+	new := model(nil, "unset", "on", "off")
+	plan := Generate("6_test", old, new)
+	// 转换时已使用新的序号，不再按重新排序映射
+	validator.Equal([]*Step{{
+		Comment:    "重新映射枚举state的值",
+		Collection: "demos",
+		Filter:     `{"state":{"$in":["unset","on","off"]}}`,
+		Update:     `[{"$set":{"state":{"$switch":{"branches":[{"case":{"$eq":["$state","unset"]},"then":{"$numberInt":"0"}},{"case":{"$eq":["$state","on"]},"then":{"$numberInt":"1"}},{"case":{"$eq":["$state","off"]},"then":{"$numberInt":"2"}}],"default":"$state"}}}}]`,
+	}}, plan.Up)
+	validator.Len(plan.Down, 1)
+	validator.Equal([]string{"demo.state: 不是枚举值名称的字符串需要手动处理"}, plan.Manual)
+}
@@ -0,0 +1,70 @@
+package migrate
+
+import (
+	"github.com/wzyjerry/windranger/internal/parser"
+)
+
+// location 结构在集合文档中的位置
+type location struct {
+	Collection *parser.Collection
+	// Prefix 存储路径前缀，以'.'结尾或为空
+	Prefix string
+	// InArray 是否位于数组中，数组中的字段无法通过updateMany直接迁移
+	InArray bool
+	// Optional 可空的上级子文档的存储路径，子文档为null或不存在时不能设置其中的字段
+	Optional []string
+}
+
+// storageKey 字段在文档中的键名，主键映射为_id
+func storageKey(field *parser.Field) string {
+	if field.Type.Kind == parser.KindPrimaryKey {
+		return "_id"
+	}
+	return field.Name
+}
+
+// locate 计算每个结构在各集合中的位置
+func locate(packages []*parser.Package) map[string][]*location {
+	structures := make(map[string]*parser.Structure)
+	for _, pack := range packages {
+		for _, structure := range pack.Structures {
+			structures[structure.Name] = structure
+		}
+	}
+	locations := make(map[string][]*location)
+	visiting := make(map[string]struct{})
+	var walk func(structure *parser.Structure, loc *location)
+	walk = func(structure *parser.Structure, loc *location) {
+		if _, ok := visiting[structure.Name]; ok {
+			return
+		}
+		visiting[structure.Name] = struct{}{}
+		defer delete(visiting, structure.Name)
+		locations[structure.Name] = append(locations[structure.Name], loc)
+		for _, field := range structure.Fields {
+			if next, ok := structures[field.Type.Raw]; ok {
+				optional := loc.Optional
+				if field.Type.Kind == parser.KindOptional {
+					optional = append(append([]string{}, loc.Optional...), loc.Prefix+storageKey(field))
+				}
+				walk(next, &location{
+					Collection: loc.Collection,
+					Prefix:     loc.Prefix + storageKey(field) + ".",
+					InArray:    loc.InArray || field.Type.Kind == parser.KindArray,
+					Optional:   optional,
+				})
+			}
+		}
+	}
+	for _, pack := range packages {
+		if pack.Collection == nil {
+			continue
+		}
+		if table, ok := structures[pack.Name]; ok {
+			walk(table, &location{
+				Collection: pack.Collection,
+			})
+		}
+	}
+	return locations
+}
@@ -0,0 +1,227 @@
+package output
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/wzyjerry/windranger/internal/region"
+)
+
+// ManifestName 输出目录中的生成清单文件名
+const ManifestName = ".windranger-manifest.json"
+
+type (
+	// Manifest 生成清单，记录每个生成器在输出目录中生成的文件
+	Manifest struct {
+		Generators map[string]*Generation `json:"generators"`
+	}
+	// Generation 一个生成器最近一次的生成结果
+	Generation struct {
+		// Version 生成时的windranger版本
+		Version string `json:"version"`
+		// Schema 模型内容的哈希
+		Schema string   `json:"schema"`
+		Files  []*Entry `json:"files"`
+	}
+	// Entry 生成的文件
+	Entry struct {
+		// Path 相对于输出目录的路径，使用'/'分隔
+		Path string `json:"path"`
+		// Hash 去除自定义区域内容后的sha256
+		Hash string `json:"hash"`
+	}
+	// tracking 清单跟踪信息
+	tracking struct {
+		root      string
+		generator string
+		version   string
+		schema    string
+	}
+)
+
+// EditedError 生成的文件在上次生成后被手动修改
+type EditedError struct {
+	Paths []string
+}
+
+func (e *EditedError) Error() string {
+	return "生成的文件被手动修改，请将修改移入自定义区域或使用--force覆盖:\n  " + strings.Join(e.Paths, "\n  ")
+}
+
+// Hash 文件内容的哈希，忽略自定义区域中的内容
+func Hash(content []byte) string {
+	sum := sha256.Sum256(region.Strip(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// ReadManifest 读取输出目录中的清单，不存在时返回空清单
+func ReadManifest(root string) (*Manifest, error) {
+	manifest := &Manifest{
+		Generators: make(map[string]*Generation),
+	}
+	content, err := os.ReadFile(filepath.Join(root, ManifestName))
+	if errors.Is(err, os.ErrNotExist) {
+		return manifest, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(content, manifest); err != nil {
+		return nil, fmt.Errorf("生成清单格式错误: %w", err)
+	}
+	if manifest.Generators == nil {
+		manifest.Generators = make(map[string]*Generation)
+	}
+	return manifest, nil
+}
+
+// Write 将清单写入输出目录，内容未变化时不重写
+func (m *Manifest) Write(root string) error {
+	content, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	content = append(content, '\n')
+	name := filepath.Join(root, ManifestName)
+	if old, err := os.ReadFile(name); err == nil && bytes.Equal(old, content) {
+		return nil
+	}
+	if err := os.MkdirAll(root, os.ModePerm); err != nil {
+		return err
+	}
+	return os.WriteFile(name, content, os.ModePerm)
+}
+
+// Track 启用生成清单，root为输出目录，generator区分写入同一目录的多个生成器，schema为模型哈希
+func (s *Set) Track(root string, generator string, version string, schema string) {
+	s.tracking = &tracking{
+		root:      root,
+		generator: generator,
+		version:   version,
+		schema:    schema,
+	}
+}
+
+// previous 读取清单，返回清单和当前生成器上次生成的文件
+func (s *Set) previous() (*Manifest, []*Entry, error) {
+	manifest, err := ReadManifest(s.tracking.root)
+	if err != nil {
+		return nil, nil, err
+	}
+	var entries []*Entry
+	if generation, ok := manifest.Generators[s.tracking.generator]; ok {
+		for _, entry := range generation.Files {
+			// 忽略超出输出目录的路径
+			name := filepath.Clean(filepath.FromSlash(entry.Path))
+			if filepath.IsAbs(name) || name == ".." || strings.HasPrefix(name, ".."+string(filepath.Separator)) {
+				continue
+			}
+			entries = append(entries, entry)
+		}
+	}
+	return manifest, entries, nil
+}
+
+// orphans 上次生成但本次不再生成且仍在磁盘上的文件
+func (s *Set) orphans(entries []*Entry) ([]*Change, error) {
+	var changes []*Change
+	for _, entry := range entries {
+		path := filepath.Join(s.tracking.root, filepath.FromSlash(entry.Path))
+		if _, ok := s.index[path]; ok {
+			continue
+		}
+		old, err := os.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, &Change{Path: path, Status: StatusOrphaned, Old: old})
+	}
+	return changes, nil
+}
+
+// edited 上次生成后被手动修改的文件
+func (s *Set) edited(entries []*Entry) ([]string, error) {
+	var paths []string
+	for _, entry := range entries {
+		path := filepath.Join(s.tracking.root, filepath.FromSlash(entry.Path))
+		content, err := os.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if Hash(content) != entry.Hash {
+			paths = append(paths, path)
+		}
+	}
+	return paths, nil
+}
+
+// custom 不再生成的文件的自定义区域中有代码时返回*region.OrphanError，删除文件会丢失这些代码
+func (s *Set) custom(entries []*Entry) error {
+	orphans, err := s.orphans(entries)
+	if err != nil {
+		return err
+	}
+	for _, orphan := range orphans {
+		regions, err := region.Extract(orphan.Old)
+		if err != nil {
+			return fmt.Errorf("%s: %w", orphan.Path, err)
+		}
+		var kept []*region.Region
+		for _, r := range regions {
+			if strings.TrimSpace(r.Body) != "" {
+				kept = append(kept, r)
+			}
+		}
+		if len(kept) != 0 {
+			return &region.OrphanError{File: orphan.Path, Regions: kept}
+		}
+	}
+	return nil
+}
+
+// sync 写入文件后删除不再生成的文件并更新清单
+func (s *Set) sync(manifest *Manifest, entries []*Entry) error {
+	orphans, err := s.orphans(entries)
+	if err != nil {
+		return err
+	}
+	for _, orphan := range orphans {
+		if err := os.Remove(orphan.Path); err != nil {
+			return err
+		}
+	}
+	generation := &Generation{
+		Version: s.tracking.version,
+		Schema:  s.tracking.schema,
+		Files:   make([]*Entry, 0, len(s.files)),
+	}
+	for _, file := range s.files {
+		name, err := filepath.Rel(s.tracking.root, file.Path)
+		if err != nil {
+			return err
+		}
+		generation.Files = append(generation.Files, &Entry{
+			Path: filepath.ToSlash(name),
+			Hash: Hash(file.Content),
+		})
+	}
+	sort.SliceStable(generation.Files, func(i, j int) bool {
+		return generation.Files[i].Path < generation.Files[j].Path
+	})
+	manifest.Generators[s.tracking.generator] = generation
+	return manifest.Write(s.tracking.root)
+}
@@ -0,0 +1,126 @@
+package output
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/wzyjerry/windranger/internal/region"
+)
+
+func TestManifest(t *testing.T) {
+	validator := require.New(t)
+	root := t.TempDir()
+	set := NewSet()
+	set.Add(filepath.Join(root, "a.go"), []byte("package model\n"))
+	set.Add(filepath.Join(root, "sub", "b.go"), []byte("package model\n"))
+	set.Track(root, "gogo", "v1.0.0", "schema")
+	validator.Nil(set.Write())
+
+	// 清单内容未变化时不重写
+	name := filepath.Join(root, ManifestName)
+	past := time.Now().Add(-time.Hour).Truncate(time.Second)
+	validator.Nil(os.Chtimes(name, past, past))
+	validator.Nil(set.Write())
+	info, err := os.Stat(name)
+	validator.Nil(err)
+	validator.True(info.ModTime().Equal(past))
+
+	manifest, err := ReadManifest(root)
+	validator.Nil(err)
+	validator.Equal(&Generation{
+		Version: "v1.0.0",
+		Schema:  "schema",
+		Files: []*Entry{
+			{Path: "a.go", Hash: Hash([]byte("package model\n"))},
+			{Path: "sub/b.go", Hash: Hash([]byte("package model\n"))},
+		},
+	}, manifest.Generators["gogo"])
+
+	// 其他生成器的文件不受影响
+	other := NewSet()
+	other.Add(filepath.Join(root, "c.js"), []byte("db\n"))
+	other.Track(root, "mongo-init", "v1.0.0", "schema")
+	validator.Nil(other.Write())
+
+	// 不再生成的文件在检查时报告，写入时删除
+	set = NewSet()
+	set.Add(filepath.Join(root, "a.go"), []byte("package model\n"))
+	set.Track(root, "gogo", "v1.0.0", "changed")
+	changes, err := set.Check()
+	validator.Nil(err)
+	validator.Equal([]*Change{{Path: filepath.Join(root, "sub", "b.go"), Status: StatusOrphaned, Old: []byte("package model\n")}}, changes)
+	validator.Nil(set.Write())
+	_, err = os.Stat(filepath.Join(root, "sub", "b.go"))
+	validator.True(os.IsNotExist(err))
+	_, err = os.Stat(filepath.Join(root, "c.js"))
+	validator.Nil(err)
+
+	manifest, err = ReadManifest(root)
+	validator.Nil(err)
+	validator.Len(manifest.Generators, 2)
+	validator.Equal("changed", manifest.Generators["gogo"].Schema)
+	validator.Len(manifest.Generators["gogo"].Files, 1)
+}
+
+func TestManifestEdited(t *testing.T) {
+	validator := require.New(t)
+	root := t.TempDir()
+	path := filepath.Join(root, "a.go")
+	generated := "package model\n\n// windranger:begin custom A\n// windranger:end\n"
+	set := NewSet()
+	set.Add(path, []byte(generated))
+	set.Track(root, "gogo", "v1.0.0", "schema")
+	validator.Nil(set.Write())
+
+	// 自定义区域中的修改不算手动修改
+	validator.Nil(os.WriteFile(path, []byte("package model\n\n// windranger:begin custom A\nvar A = 1\n// windranger:end\n"), os.ModePerm))
+	validator.Nil(set.Write())
+
+	validator.Nil(os.WriteFile(path, []byte("package model\n\nvar B = 1\n"), os.ModePerm))
+	err := set.Write()
+	validator.Equal(&EditedError{Paths: []string{path}}, err)
+	content, err := os.ReadFile(path)
+	validator.Nil(err)
+	validator.Equal("package model\n\nvar B = 1\n", string(content))
+
+	set.Force = true
+	validator.Nil(set.Write())
+	content, err = os.ReadFile(path)
+	validator.Nil(err)
+	validator.Equal(generated, string(content))
+}
+
+func TestManifestOrphanRegion(t *testing.T) {
+	validator := require.New(t)
+	root := t.TempDir()
+	a, b := filepath.Join(root, "a.go"), filepath.Join(root, "b.go")
+	set := NewSet()
+	set.Add(a, []byte("package model\n"))
+	set.Add(b, []byte("package model\n\n// windranger:begin custom B\n// windranger:end\n"))
+	set.Track(root, "gogo", "v1.0.0", "schema")
+	validator.Nil(set.Write())
+
+	edited := "package model\n\n// windranger:begin custom B\nfunc (B) Name() string { return \"b\" }\n// windranger:end\n"
+	validator.Nil(os.WriteFile(b, []byte(edited), os.ModePerm))
+
+	// 不再生成的文件的自定义区域中有代码时不删除
+	set = NewSet()
+	set.Add(a, []byte("package model\n\nvar A = 1\n"))
+	set.Track(root, "gogo", "v1.0.0", "changed")
+	err := set.Write()
+	validator.Equal(&region.OrphanError{File: b, Regions: []*region.Region{{Name: "B", Body: "func (B) Name() string { return \"b\" }\n", Line: 3}}}, err)
+	content, err := os.ReadFile(b)
+	validator.Nil(err)
+	validator.Equal(edited, string(content))
+	content, err = os.ReadFile(a)
+	validator.Nil(err)
+	validator.Equal("package model\n", string(content))
+
+	set.Force = true
+	validator.Nil(set.Write())
+	_, err = os.Stat(b)
+	validator.True(os.IsNotExist(err))
+}
@@ -0,0 +1,194 @@
+package output
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// File 生成的文件
+type File struct {
+	// Path 目标路径
+	Path    string
+	Content []byte
+}
+
+// Set 内存中的生成结果，按添加顺序保存
+type Set struct {
+	// Force 覆盖被手动修改的文件，仅在启用清单时有效
+	Force bool
+
+	files    []*File
+	index    map[string]int
+	tracking *tracking
+}
+
+// Status 文件与磁盘比较的结果
+type Status int
+
+const (
+	// StatusModified 磁盘上的文件内容不同
+	StatusModified Status = iota
+	// StatusMissing 磁盘上不存在该文件
+	StatusMissing
+	// StatusOrphaned 清单中记录的文件不再生成
+	StatusOrphaned
+)
+
+// Change 与磁盘不一致的文件
+type Change struct {
+	Path   string
+	Status Status
+	// Old 磁盘上的内容，文件不存在时为nil
+	Old []byte
+	// New 生成的内容，文件不再生成时为nil
+	New []byte
+}
+
+func NewSet() *Set {
+	return &Set{
+		index: make(map[string]int),
+	}
+}
+
+// Add 添加文件，同一路径再次添加时覆盖之前的内容
+func (s *Set) Add(path string, content []byte) {
+	path = filepath.Clean(path)
+	if i, ok := s.index[path]; ok {
+		s.files[i].Content = content
+		return
+	}
+	s.index[path] = len(s.files)
+	s.files = append(s.files, &File{Path: path, Content: content})
+}
+
+// Files 全部文件
+func (s *Set) Files() []*File {
+	return s.files
+}
+
+// Write 将内容变化的文件写入磁盘
+//
+// 启用清单时，先检查上次生成的文件是否被手动修改、不再生成的文件的自定义区域中是否有代码，
+// 写入后删除不再生成的文件并更新清单
+func (s *Set) Write() error {
+	var (
+		manifest *Manifest
+		entries  []*Entry
+		err      error
+	)
+	if s.tracking != nil {
+		manifest, entries, err = s.previous()
+		if err != nil {
+			return err
+		}
+		edited, err := s.edited(entries)
+		if err != nil {
+			return err
+		}
+		if len(edited) != 0 && !s.Force {
+			return &EditedError{Paths: edited}
+		}
+		if !s.Force {
+			if err := s.custom(entries); err != nil {
+				return err
+			}
+		}
+	}
+	for _, file := range s.files {
+		// 内容未变化时不重写，保持修改时间
+		if old, err := os.ReadFile(file.Path); err == nil && bytes.Equal(old, file.Content) {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(file.Path), os.ModePerm); err != nil {
+			return err
+		}
+		if err := os.WriteFile(file.Path, file.Content, os.ModePerm); err != nil {
+			return err
+		}
+	}
+	if s.tracking != nil {
+		return s.sync(manifest, entries)
+	}
+	return nil
+}
+
+// Check 与磁盘上的文件比较，返回不一致的文件，启用清单时包括不再生成的文件
+func (s *Set) Check() ([]*Change, error) {
+	var changes []*Change
+	for _, file := range s.files {
+		old, err := os.ReadFile(file.Path)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			changes = append(changes, &Change{Path: file.Path, Status: StatusMissing, New: file.Content})
+		case err != nil:
+			return nil, err
+		case !bytes.Equal(old, file.Content):
+			changes = append(changes, &Change{Path: file.Path, Status: StatusModified, Old: old, New: file.Content})
+		}
+	}
+	if s.tracking != nil {
+		_, entries, err := s.previous()
+		if err != nil {
+			return nil, err
+		}
+		orphans, err := s.orphans(entries)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, orphans...)
+	}
+	return changes, nil
+}
+
+func (c *Change) String() string {
+	switch c.Status {
+	case StatusMissing:
+		return fmt.Sprintf("%s: 文件缺失，需要重新生成", c.Path)
+	case StatusOrphaned:
+		return fmt.Sprintf("%s: 文件不再生成，需要删除", c.Path)
+	}
+	return fmt.Sprintf("%s: 文件已过期，需要重新生成", c.Path)
+}
+
+// Diff 磁盘内容到生成内容的统一格式差异
+func (c *Change) Diff() (string, error) {
+	name := filepath.ToSlash(c.Path)
+	from, to := "a/"+name, "b/"+name
+	// 绝对路径不添加前缀
+	if filepath.IsAbs(c.Path) {
+		from, to = name, name
+	}
+	switch c.Status {
+	case StatusMissing:
+		from = "/dev/null"
+	case StatusOrphaned:
+		to = "/dev/null"
+	}
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        lines(c.Old),
+		B:        lines(c.New),
+		FromFile: from,
+		ToFile:   to,
+		Context:  3,
+	})
+}
+
+// lines 按行切分，每行保留换行符，末行缺少换行符时补齐
+func lines(content []byte) []string {
+	if len(content) == 0 {
+		return nil
+	}
+	result := strings.SplitAfter(string(content), "\n")
+	if last := result[len(result)-1]; last == "" {
+		result = result[:len(result)-1]
+	} else {
+		result[len(result)-1] = last + "\n"
+	}
+	return result
+}
@@ -0,0 +1,89 @@
+package output
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSet(t *testing.T) {
+	validator := require.New(t)
+	dir := t.TempDir()
+	set := NewSet()
+	set.Add(filepath.Join(dir, "a", "a.go"), []byte("package a\n"))
+	set.Add(filepath.Join(dir, "b.go"), []byte("package b\n"))
+	set.Add(filepath.Join(dir, "a", "..", "b.go"), []byte("package b\n\nvar B = 1\n"))
+	validator.Len(set.Files(), 2)
+
+	changes, err := set.Check()
+	validator.Nil(err)
+	validator.Len(changes, 2)
+	validator.Equal(StatusMissing, changes[0].Status)
+
+	validator.Nil(set.Write())
+	changes, err = set.Check()
+	validator.Nil(err)
+	validator.Empty(changes)
+
+	validator.Nil(os.WriteFile(filepath.Join(dir, "b.go"), []byte("package b\n\nvar B = 2\n"), os.ModePerm))
+	changes, err = set.Check()
+	validator.Nil(err)
+	validator.Len(changes, 1)
+	validator.Equal(StatusModified, changes[0].Status)
+	validator.Equal(filepath.Join(dir, "b.go")+": 文件已过期，需要重新生成", changes[0].String())
+}
+
+func TestDiff(t *testing.T) {
+	validator := require.New(t)
+	diff, err := (&Change{
+		Path:   "model/demo.go",
+		Status: StatusModified,
+		Old:    []byte("package model\n\n// Name 名字\n"),
+		New:    []byte("package model\n\n// Name 姓名\n"),
+	}).Diff()
+	validator.Nil(err)
+	validator.Equal(`--- a/model/demo.go
++++ b/model/demo.go
+@@ -1,3 +1,3 @@
+ package model
+ 
+-// Name 名字
++// Name 姓名
+`, diff)
+
+	diff, err = (&Change{
+		Path:   "model/demo.go",
+		Status: StatusMissing,
+		New:    []byte("package model\n"),
+	}).Diff()
+	validator.Nil(err)
+	validator.Equal(`--- /dev/null
++++ b/model/demo.go
+@@ -0,0 +1 @@
++package model
+`, diff)
+}
+
+func TestWriteUnchanged(t *testing.T) {
+	validator := require.New(t)
+	path := filepath.Join(t.TempDir(), "a.go")
+	set := NewSet()
+	set.Add(path, []byte("package a\n"))
+	validator.Nil(set.Write())
+	past := time.Now().Add(-time.Hour).Truncate(time.Second)
+	validator.Nil(os.Chtimes(path, past, past))
+	// 内容未变化时不重写
+	validator.Nil(set.Write())
+	info, err := os.Stat(path)
+	validator.Nil(err)
+	validator.Equal(past, info.ModTime())
+
+	set.Add(path, []byte("package b\n"))
+	validator.Nil(set.Write())
+	info, err = os.Stat(path)
+	validator.Nil(err)
+	validator.NotEqual(past, info.ModTime())
+}
@@ -0,0 +1,80 @@
+package parser
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// schema 生成包含n个表的模型，每个表有一个枚举和若干字段
+func schema(n int) [][]byte {
+	docs := make([][]byte, n)
+	for i := range docs {
+		var builder strings.Builder
+		fmt.Fprintf(&builder, `version: v2
+kind: Model
+metadata:
+  name: table%d
+  indexes:
+    - keys: [field0, -field1]
+spec:
+  # 表%d
+  table%d:
+    id!: objectid # 主键
+    status%d: # 状态
+      - active # 启用
+      - inactive # 停用
+`, i, i, i, i)
+		for j := 0; j < 30; j++ {
+			fmt.Fprintf(&builder, "    field%d:\n      type: string\n      description: 字段%d\n      default: value%d\n", j, j, j)
+		}
+		docs[i] = []byte(builder.String())
+	}
+	return docs
+}
+
+func parseSchema(docs [][]byte, workers int) ([]*Package, []error) {
+	p := NewParser().SetWorkers(workers)
+	for i, doc := range docs {
+		p.AddYamlFile(fmt.Sprintf("table%d.yaml", i), doc)
+	}
+	return p.Parse()
+}
+
+func TestParseDeterministic(t *testing.T) {
+	validator := require.New(t)
+	docs := schema(50)
+	expected, errs := parseSchema(docs, 1)
+	validator.Nil(errs)
+	validator.Len(expected, 50)
+	for i := 0; i < 5; i++ {
+		packages, errs := parseSchema(docs, 8)
+		validator.Nil(errs)
+		validator.Equal(expected, packages)
+	}
+	// 错误按块的顺序合并，无法解析的块之后的块不参与链接
+	docs[10] = []byte("version: v0")
+	docs[20] = []byte("version: v0")
+	_, errs = parseSchema(docs, 8)
+	validator.Equal([]error{&Error{Pos: Position{File: "table10.yaml"}, Err: fmt.Errorf("未知版本号: v0")}}, errs)
+}
+
+func BenchmarkParse(b *testing.B) {
+	docs := schema(200)
+	for _, c := range []struct {
+		name    string
+		workers int
+	}{{"sequential", 1}, {"parallel", runtime.GOMAXPROCS(0)}} {
+		workers := c.workers
+		b.Run(c.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, errs := parseSchema(docs, workers); errs != nil {
+					b.Fatal(errs[0])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,232 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// metadata model元数据
+type metadata struct {
+	// Name 表名
+	Name       string    `yaml:"name"`
+	Collection string    `yaml:"collection"`
+	Database   string    `yaml:"database"`
+	Indexes    []index   `yaml:"indexes"`
+	Text       []string  `yaml:"text"`
+	TTL        *ttl      `yaml:"ttl"`
+	ShardKey   *shardKey `yaml:"shard_key"`
+}
+
+// index 索引定义，'-'前缀表示降序
+type index struct {
+	Name   string   `yaml:"name"`
+	Keys   []string `yaml:"keys"`
+	Unique bool     `yaml:"unique"`
+}
+
+// ttl TTL索引定义
+type ttl struct {
+	Field       string `yaml:"field"`
+	ExpireAfter int32  `yaml:"expire_after"`
+}
+
+// shardKey 分片键定义
+type shardKey struct {
+	Keys   []string `yaml:"keys"`
+	Hashed bool     `yaml:"hashed"`
+}
+
+// hasCollection 是否声明了集合元数据
+func (m *metadata) hasCollection() bool {
+	return m.Collection != "" || m.Database != "" || len(m.Indexes) != 0 ||
+		len(m.Text) != 0 || m.TTL != nil || m.ShardKey != nil
+}
+
+// parseIndexKey 解析索引键
+func parseIndexKey(key string) *IndexKey {
+	if strings.HasPrefix(key, "-") {
+		return &IndexKey{
+			Field: key[1:],
+			Order: IndexDescending,
+		}
+	}
+	return &IndexKey{
+		Field: key,
+		Order: IndexAscending,
+	}
+}
+
+// parseCollection 解析集合元数据
+func (d *document) parseCollection(meta *metadata) *Collection {
+	collection := &Collection{
+		Name:     meta.Collection,
+		Database: meta.Database,
+		Indexes:  make([]*Index, 0, len(meta.Indexes)),
+	}
+	if collection.Name == "" {
+		collection.Name = meta.Name
+	}
+	for _, idx := range meta.Indexes {
+		if len(idx.Keys) == 0 {
+			d.errors = append(d.errors, fmt.Errorf("索引缺少字段: %s", meta.Name))
+			continue
+		}
+		keys := make([]*IndexKey, len(idx.Keys))
+		for i, key := range idx.Keys {
+			keys[i] = parseIndexKey(key)
+		}
+		collection.Indexes = append(collection.Indexes, &Index{
+			Name:   idx.Name,
+			Keys:   keys,
+			Unique: idx.Unique,
+		})
+	}
+	if len(meta.Text) != 0 {
+		keys := make([]*IndexKey, len(meta.Text))
+		for i, key := range meta.Text {
+			keys[i] = &IndexKey{
+				Field: key,
+				Order: IndexText,
+			}
+		}
+		collection.Indexes = append(collection.Indexes, &Index{
+			Keys: keys,
+		})
+	}
+	if meta.TTL != nil {
+		if meta.TTL.ExpireAfter < 0 {
+			d.errors = append(d.errors, fmt.Errorf("TTL秒数不能为负: %s", meta.Name))
+		}
+		expireAfter := meta.TTL.ExpireAfter
+		collection.Indexes = append(collection.Indexes, &Index{
+			Keys: []*IndexKey{{
+				Field: meta.TTL.Field,
+				Order: IndexAscending,
+			}},
+			ExpireAfter: &expireAfter,
+		})
+	}
+	if meta.ShardKey != nil {
+		if len(meta.ShardKey.Keys) == 0 {
+			d.errors = append(d.errors, fmt.Errorf("分片键缺少字段: %s", meta.Name))
+		}
+		if meta.ShardKey.Hashed && len(meta.ShardKey.Keys) != 1 {
+			d.errors = append(d.errors, fmt.Errorf("哈希分片键只能包含一个字段: %s", meta.Name))
+		}
+		keys := make([]*IndexKey, len(meta.ShardKey.Keys))
+		for i, key := range meta.ShardKey.Keys {
+			keys[i] = parseIndexKey(key)
+			if meta.ShardKey.Hashed {
+				keys[i].Order = IndexHashed
+			}
+		}
+		collection.ShardKey = &ShardKey{
+			Keys: keys,
+		}
+	}
+	return collection
+}
+
+// resolveField 按'.'分隔的路径查找表中的字段，返回字段及其存储路径
+func resolveField(table *Structure, path string, structures map[string]*Structure) (*Field, string, error) {
+	segments := strings.Split(path, ".")
+	keys := make([]string, len(segments))
+	current := table
+	for i, segment := range segments {
+		var field *Field
+		for _, f := range current.Fields {
+			if f.Name == segment {
+				field = f
+				break
+			}
+		}
+		if field == nil {
+			return nil, "", fmt.Errorf("字段不存在: %s.%s", table.Name, path)
+		}
+		keys[i] = segment
+		if field.Type.Kind == KindPrimaryKey {
+			keys[i] = "_id"
+		}
+		if i == len(segments)-1 {
+			return field, strings.Join(keys, "."), nil
+		}
+		next, ok := structures[field.Type.Raw]
+		if !ok {
+			return nil, "", fmt.Errorf("字段不是结构: %s.%s", table.Name, strings.Join(segments[:i+1], "."))
+		}
+		current = next
+	}
+	return nil, "", fmt.Errorf("字段不存在: %s.%s", table.Name, path)
+}
+
+// indexName 生成与MongoDB一致的默认索引名
+func indexName(keys []*IndexKey) string {
+	parts := make([]string, 0, len(keys)<<1)
+	for _, key := range keys {
+		parts = append(parts, key.Key)
+		switch key.Order {
+		case IndexAscending:
+			parts = append(parts, "1")
+		case IndexDescending:
+			parts = append(parts, "-1")
+		case IndexText:
+			parts = append(parts, "text")
+		case IndexHashed:
+			parts = append(parts, "hashed")
+		}
+	}
+	return strings.Join(parts, "_")
+}
+
+// validateCollections 校验集合元数据引用的字段，补全存储路径和默认索引名
+func (p *parser) validateCollections(packages []*Package) {
+	structures := make(map[string]*Structure)
+	for _, pack := range packages {
+		for _, structure := range pack.Structures {
+			structures[structure.Name] = structure
+		}
+	}
+	collections := make([]string, 0)
+	for _, pack := range packages {
+		if pack.Collection == nil {
+			continue
+		}
+		collections = append(collections, pack.Collection.Database+"."+pack.Collection.Name)
+		var table *Structure
+		for _, structure := range pack.Structures {
+			if structure.Name == pack.Name {
+				table = structure
+			}
+		}
+		resolve := func(key *IndexKey) *Field {
+			field, path, err := resolveField(table, key.Field, structures)
+			if err != nil {
+				p.errors = append(p.errors, err)
+				return nil
+			}
+			key.Key = path
+			return field
+		}
+		for _, index := range pack.Collection.Indexes {
+			for _, key := range index.Keys {
+				field := resolve(key)
+				if field != nil && index.ExpireAfter != nil && field.Type.Raw != "datetime" {
+					p.errors = append(p.errors, fmt.Errorf("TTL字段必须为datetime: %s.%s", pack.Name, key.Field))
+				}
+			}
+			if index.Name == "" {
+				index.Name = indexName(index.Keys)
+			}
+		}
+		if pack.Collection.ShardKey != nil {
+			for _, key := range pack.Collection.ShardKey.Keys {
+				resolve(key)
+			}
+		}
+	}
+	for _, id := range findConflict(collections, func(id string) string {
+		return id
+	}) {
+		p.errors = append(p.errors, fmt.Errorf("重复的集合: %s", strings.TrimPrefix(id, ".")))
+	}
+}
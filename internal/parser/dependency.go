@@ -0,0 +1,158 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+
+	"gopkg.in/yaml.v3"
+)
+
+// VendorDir git依赖下载到配置目录中的位置
+const VendorDir = ".windranger/deps"
+
+// Dependency windranger.yaml中声明的依赖项目
+//
+// 依赖项目的包可以被引用，默认不生成；依赖项目自身的依赖不会展开
+type Dependency struct {
+	// Name 依赖名，依赖项目公共包的包名为<name>_type
+	Name string `yaml:"name"`
+	// Path 本地配置目录，相对于当前配置目录，直接读取
+	Path string `yaml:"path,omitempty"`
+	// Git git仓库地址，下载到VendorDir后读取
+	Git string `yaml:"git,omitempty"`
+	// Version git依赖固定的tag、分支或提交
+	Version string `yaml:"version,omitempty"`
+	// Dir 配置目录在git仓库中的路径，默认为仓库根目录
+	Dir string `yaml:"dir,omitempty"`
+	// SHA256 固定的依赖项目内容哈希，见Digest
+	SHA256 string `yaml:"sha256,omitempty"`
+}
+
+// dependencyName 合法的依赖名
+var dependencyName = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// Root 依赖项目的配置目录，root为当前配置目录
+func (d *Dependency) Root(root string) string {
+	if d.Git == "" {
+		return path.Join(root, d.Path)
+	}
+	return path.Join(root, VendorDir, d.Name, d.Dir)
+}
+
+// String 依赖来源
+func (d *Dependency) String() string {
+	if d.Git == "" {
+		return d.Path
+	}
+	source := d.Git + "@" + d.Version
+	if d.Dir != "" {
+		source += "#" + d.Dir
+	}
+	return source
+}
+
+// Dependencies 读取配置目录中windranger.yaml声明的依赖项目
+func Dependencies(root string) ([]*Dependency, error) {
+	return (&resolver{}).dependencies(root)
+}
+
+// DependenciesFS 与Dependencies相同，从fsys中的dir目录读取
+func DependenciesFS(fsys fs.FS, dir string) ([]*Dependency, error) {
+	return (&resolver{fsys: fsys}).dependencies(dir)
+}
+
+// dependencies 读取并校验依赖声明
+func (r *resolver) dependencies(root string) ([]*Dependency, error) {
+	content, err := r.readFile(path.Join(root, Profile))
+	if err != nil {
+		return nil, err
+	}
+	var cfg windranger
+	if err := yaml.Unmarshal(content, &cfg); err != nil {
+		return nil, err
+	}
+	names := make(map[string]struct{}, len(cfg.Dependencies))
+	for _, dep := range cfg.Dependencies {
+		if !dependencyName.MatchString(dep.Name) {
+			return nil, fmt.Errorf("依赖名只能包含小写字母、数字和'_'且以字母开头: %q", dep.Name)
+		}
+		if _, ok := names[dep.Name]; ok {
+			return nil, fmt.Errorf("重复的依赖: %s", dep.Name)
+		}
+		names[dep.Name] = struct{}{}
+		switch {
+		case (dep.Path == "") == (dep.Git == ""):
+			return nil, fmt.Errorf("依赖%s必须且只能指定path或git", dep.Name)
+		case dep.Git != "" && dep.Version == "":
+			return nil, fmt.Errorf("git依赖%s缺少version", dep.Name)
+		case dep.Git == "" && (dep.Version != "" || dep.Dir != ""):
+			return nil, fmt.Errorf("本地依赖%s不支持version和dir", dep.Name)
+		case dep.Dir != "" && !fs.ValidPath(dep.Dir):
+			return nil, fmt.Errorf("依赖%s的dir必须为仓库内的相对路径: %s", dep.Name, dep.Dir)
+		case dep.SHA256 != "" && !sha256Hex.MatchString(dep.SHA256):
+			return nil, fmt.Errorf("依赖%s的sha256必须为64位小写十六进制: %s", dep.Name, dep.SHA256)
+		}
+	}
+	return cfg.Dependencies, nil
+}
+
+// addProject 添加配置目录中的资源文件及依赖项目的资源文件，fsys为nil时使用操作系统文件系统
+func (p *parser) addProject(fsys fs.FS, root string) *parser {
+	r := &resolver{fsys: fsys}
+	if _, ok := p.addResources(r, root); !ok {
+		return p
+	}
+	deps, err := r.dependencies(root)
+	if err != nil {
+		p.errors = append(p.errors, err)
+		return p
+	}
+	for _, dep := range deps {
+		if _, err := r.stat(path.Join(dep.Root(root), Profile)); errors.Is(err, fs.ErrNotExist) && dep.Git != "" {
+			p.errors = append(p.errors, fmt.Errorf("依赖%s尚未下载，请执行windranger deps update", dep.Name))
+			return p
+		}
+		p.module = dep.Name
+		digest, ok := p.addResources(&resolver{fsys: fsys}, dep.Root(root))
+		p.module = ""
+		if !ok {
+			return p
+		}
+		if dep.SHA256 != "" && digest != dep.SHA256 {
+			p.errors = append(p.errors, Mismatch("依赖"+dep.Name, dep.SHA256, digest))
+			return p
+		}
+	}
+	return p
+}
+
+// addResources 添加配置目录中windranger.yaml列出的资源文件并校验固定的sha256，返回内容哈希，出错时记录错误并返回false
+func (p *parser) addResources(r *resolver, root string) (string, bool) {
+	fail := func(err error) (string, bool) {
+		if p.module != "" {
+			err = fmt.Errorf("依赖%s: %w", p.module, err)
+		}
+		p.errors = append(p.errors, err)
+		return "", false
+	}
+	resources, err := r.resolve(root)
+	if err != nil {
+		return fail(err)
+	}
+	d := newDigest(root)
+	for _, resource := range resources {
+		content, err := r.read(resource)
+		if err != nil {
+			return fail(err)
+		}
+		if err := r.pinned[resource].Verify(content); err != nil {
+			return fail(err)
+		}
+		d.add(resource, content)
+		p.AddYamlStream(resource, content)
+	}
+	return d.String(), true
+}
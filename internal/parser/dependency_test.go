@@ -0,0 +1,105 @@
+package parser
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDependencies(t *testing.T) {
+	validator := require.New(t)
+	common := &fstest.MapFile{Data: []byte(`version: v1
+kind: Model
+metadata:
+  name: common
+spec:
+  # 性别
+  gender:
+    - male # 男
+    - female # 女
+`)}
+	fsys := fstest.MapFS{
+		"app/windranger.yaml": {Data: []byte(`version: v1
+kind: Windranger
+resources:
+  - common.yaml
+  - user.yaml
+dependencies:
+  - name: base
+    path: ../base
+  - name: shared
+    git: https://example.com/shared.git
+    version: v1.0.0
+    dir: model
+`)},
+		"app/common.yaml": common,
+		"app/user.yaml": {Data: []byte(`version: v1
+kind: Model
+metadata:
+  name: user
+spec:
+  # 用户
+  user:
+    id!: objectid # 主键
+    gender: gender # 性别
+    address: address # 地址
+`)},
+		"base/windranger.yaml": {Data: []byte("version: v1\nkind: Windranger\nresources:\n  - common.yaml\n")},
+		"base/common.yaml": {Data: []byte(`version: v1
+kind: Model
+metadata:
+  name: common
+spec:
+  # 地址
+  address:
+    city: string # 城市
+`)},
+	}
+	deps, err := DependenciesFS(fsys, "app")
+	validator.Nil(err)
+	validator.Equal("base/windranger.yaml", deps[0].Root("app")+"/"+Profile)
+	validator.Equal("app/.windranger/deps/shared/model", deps[1].Root("app"))
+	validator.Equal("https://example.com/shared.git@v1.0.0#model", deps[1].String())
+
+	_, errs := NewParser().AddYamlFS(fsys, "app").Parse()
+	validator.Len(errs, 1)
+	validator.EqualError(errs[0], "依赖shared尚未下载，请执行windranger deps update")
+
+	fsys["app/.windranger/deps/shared/model/windranger.yaml"] = &fstest.MapFile{Data: []byte("version: v1\nkind: Windranger\nresources:\n  - common.yaml\n")}
+	fsys["app/.windranger/deps/shared/model/common.yaml"] = common
+	packages, errs := NewParser().AddYamlFS(fsys, "app").Parse()
+	validator.Nil(errs)
+	names := make(map[string]string)
+	for _, pack := range packages {
+		names[pack.Name] = pack.Module
+	}
+	// 各项目的公共包相互独立
+	validator.Equal(map[string]string{"base_type": "base", "shared_type": "shared", "type": "", "user": ""}, names)
+	own := Own(packages)
+	validator.Len(own, 2)
+	validator.Equal(CommonPackage, own[0].Name)
+	validator.Equal("user", own[1].Name)
+}
+
+func TestDependenciesFault(t *testing.T) {
+	validator := require.New(t)
+	for _, c := range []struct {
+		dependencies string
+		err          string
+	}{
+		{"  - name: Base\n    path: ../base\n", `依赖名只能包含小写字母、数字和'_'且以字母开头: "Base"`},
+		{"  - name: base\n    path: ../base\n  - name: base\n    path: ../other\n", "重复的依赖: base"},
+		{"  - name: base\n", "依赖base必须且只能指定path或git"},
+		{"  - name: base\n    path: ../base\n    git: https://example.com/base.git\n", "依赖base必须且只能指定path或git"},
+		{"  - name: base\n    git: https://example.com/base.git\n", "git依赖base缺少version"},
+		{"  - name: base\n    path: ../base\n    version: v1\n", "本地依赖base不支持version和dir"},
+		{"  - name: base\n    git: https://example.com/base.git\n    version: v1\n    dir: ../x\n", "依赖base的dir必须为仓库内的相对路径: ../x"},
+	} {
+		fsys := fstest.MapFS{
+			"windranger.yaml": {Data: []byte("version: v1\nkind: Windranger\ndependencies:\n" + c.dependencies)},
+		}
+		_, err := DependenciesFS(fsys, ".")
+		validator.EqualError(err, c.err)
+	}
+}
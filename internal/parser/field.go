@@ -0,0 +1,242 @@
+package parser
+
+import (
+	"gopkg.in/yaml.v3"
+)
+
+// tagStructure 强制将字典解析为结构的yaml标签
+const tagStructure = "!struct"
+
+// fieldAttributes 长格式字段允许的属性
+var fieldAttributes = map[string]struct{}{
+	"type":        {},
+	"optional":    {},
+	"array":       {},
+	"primary":     {},
+	"description": {},
+	"default":     {},
+	"deprecated":  {},
+	"was":         {},
+	"aliases":     {},
+}
+
+// IsLongForm 判断字典是否为长格式字段定义
+//
+// 长格式字段必须包含type属性，且仅包含已知属性；
+// 字段名恰好与属性重名的结构可以使用!struct标签声明
+func IsLongForm(node *yaml.Node) bool {
+	if node.Kind != yaml.MappingNode || node.Tag == tagStructure {
+		return false
+	}
+	var hasType bool
+	for i := 0; i < len(node.Content)>>1; i++ {
+		key := node.Content[i<<1].Value
+		if _, ok := fieldAttributes[key]; !ok {
+			return false
+		}
+		if key == "type" {
+			hasType = true
+		}
+	}
+	return hasType
+}
+
+// parseDeprecation 解析废弃标记
+//
+//	deprecated: true
+//	deprecated: 不再使用
+//	deprecated: {reason: 不再使用, replacement: full_name}
+func (d *document) parseDeprecation(name string, node *yaml.Node) *Deprecation {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		var b bool
+		if node.Tag == "!!bool" && node.Decode(&b) == nil {
+			if b {
+				return &Deprecation{}
+			}
+			return nil
+		}
+		return &Deprecation{
+			Reason: trimComment(node.Value),
+		}
+	case yaml.MappingNode:
+		deprecation := new(Deprecation)
+		for i := 0; i < len(node.Content)>>1; i++ {
+			attr, value := node.Content[i<<1].Value, node.Content[i<<1|1]
+			if value.Kind != yaml.ScalarNode {
+				d.errorf(value, "%s的废弃属性%s必须为标量", name, attr)
+				continue
+			}
+			switch attr {
+			case "reason":
+				deprecation.Reason = trimComment(value.Value)
+			case "replacement":
+				deprecation.Replacement = value.Value
+			default:
+				d.errorf(node.Content[i<<1], "%s的未知废弃属性: %s", name, attr)
+			}
+		}
+		return deprecation
+	}
+	d.errorf(node, "%s的废弃标记无效", name)
+	return nil
+}
+
+// parseLongFormEnumField 解析长格式枚举值
+//
+//   - male: {description: 男, deprecated: 使用man替代}
+func (d *document) parseLongFormEnumField(node *yaml.Node) *EnumField {
+	if len(node.Content) != 2 || node.Content[0].Kind != yaml.ScalarNode || node.Content[1].Kind != yaml.MappingNode {
+		d.errorf(node, "长格式枚举值必须为单个键值对")
+		return nil
+	}
+	key, value := node.Content[0], node.Content[1]
+	field := &EnumField{
+		Name:     key.Value,
+		Comment:  parseComment(node.HeadComment, key.HeadComment, key.LineComment, value.LineComment),
+		Suppress: parseSuppress(node.HeadComment, key.HeadComment, key.LineComment, value.LineComment),
+		Pos:      d.pos(key),
+	}
+	for i := 0; i < len(value.Content)>>1; i++ {
+		attr, v := value.Content[i<<1].Value, value.Content[i<<1|1]
+		switch attr {
+		case "description":
+			if v.Kind != yaml.ScalarNode {
+				d.errorf(v, "枚举值%s的描述必须为标量", field.Name)
+				continue
+			}
+			field.Comment = trimComment(v.Value)
+		case "deprecated":
+			field.Deprecated = d.parseDeprecation(field.Name, v)
+		default:
+			d.errorf(value.Content[i<<1], "枚举值%s的未知属性: %s", field.Name, attr)
+		}
+	}
+	return field
+}
+
+// parseBool 解析布尔属性
+func (d *document) parseBool(field *Field, key string, node *yaml.Node) bool {
+	var b bool
+	if err := node.Decode(&b); err != nil {
+		d.errorf(node, "字段%s的属性%s必须为布尔值", field.Name, key)
+		return false
+	}
+	return b
+}
+
+// setKind 设置长格式字段属性
+func (d *document) setKind(field *Field, kind Kind) {
+	if field.Type.Kind != KindNormal && field.Type.Kind != kind {
+		d.errorAt(field.Pos, "字段%s的属性冲突: %s, %s", field.Name, kindName[field.Type.Kind], kindName[kind])
+		return
+	}
+	field.Type.Kind = kind
+}
+
+// parseLongForm 解析长格式字段定义
+//
+//	name:
+//	  type: string
+//	  optional: true
+//	  description: 名称
+//	  default: unknown
+//	  deprecated:
+//	    reason: 不再使用
+//	    replacement: full_name
+//	  was: title
+//	  aliases: [caption]
+//
+// 字段通过type内联定义结构或枚举时，废弃标记同时作用于该类型
+func (d *document) parseLongForm(field *Field, key *yaml.Node, node *yaml.Node) bool {
+	var typeNode *yaml.Node
+	var description string
+	for i := 0; i < len(node.Content)>>1; i++ {
+		attr, value := node.Content[i<<1].Value, node.Content[i<<1|1]
+		switch attr {
+		case "type":
+			typeNode = value
+		case "optional":
+			if d.parseBool(field, attr, value) {
+				d.setKind(field, KindOptional)
+			}
+		case "array":
+			if d.parseBool(field, attr, value) {
+				d.setKind(field, KindArray)
+			}
+		case "primary":
+			if d.parseBool(field, attr, value) {
+				d.setKind(field, KindPrimaryKey)
+			}
+		case "description":
+			if value.Kind != yaml.ScalarNode {
+				d.errorf(value, "字段%s的描述必须为标量", field.Name)
+				continue
+			}
+			description = value.Value
+		case "default":
+			if value.Kind != yaml.ScalarNode {
+				d.errorf(value, "字段%s的默认值必须为标量", field.Name)
+				continue
+			}
+			def := value.Value
+			field.Default = &def
+		case "deprecated":
+			field.Deprecated = d.parseDeprecation(field.Name, value)
+		case "was":
+			if value.Kind != yaml.ScalarNode {
+				d.errorf(value, "字段%s的曾用名必须为标量", field.Name)
+				continue
+			}
+			field.Aliases = append(field.Aliases, value.Value)
+		case "aliases":
+			var aliases []string
+			if err := value.Decode(&aliases); err != nil {
+				d.errorf(value, "字段%s的曾用名必须为字符串数组", field.Name)
+				continue
+			}
+			field.Aliases = append(field.Aliases, aliases...)
+		}
+	}
+	if len(field.Aliases) != 0 && field.Type.Kind == KindPrimaryKey {
+		d.errorAt(field.Pos, "主键%s不能设置曾用名", field.Name)
+	}
+	if !d.parseValue(field, key, typeNode) {
+		d.errorAt(field.Pos, "字段%s的类型无效", field.Name)
+		return false
+	}
+	// 内联定义的类型随定义字段废弃
+	switch typeNode.Kind {
+	case yaml.SequenceNode:
+		d.enums[len(d.enums)-1].Deprecated = field.Deprecated
+	case yaml.MappingNode:
+		d.structures[len(d.structures)-1].Deprecated = field.Deprecated
+	}
+	if description != "" {
+		field.Comment = trimComment(description)
+	}
+	return true
+}
+
+// checkAliases 检查曾用名与字段名或其他曾用名冲突
+func (d *document) checkAliases(fields []*Field) {
+	type name struct {
+		name string
+		pos  Position
+	}
+	names := make([]name, 0, len(fields))
+	for _, field := range fields {
+		names = append(names, name{field.Name, field.Pos})
+		for _, alias := range field.Aliases {
+			names = append(names, name{alias, field.Pos})
+		}
+	}
+	if len(names) == len(fields) {
+		return
+	}
+	for _, n := range findConflictItems(names, func(n name) string {
+		return n.name
+	}) {
+		d.errorAt(n.pos, "曾用名冲突: %v", n.name)
+	}
+}
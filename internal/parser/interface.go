@@ -2,14 +2,31 @@ package parser
 
 import "strings"
 
+// CommonPackage 公共包名，不含表的模型合并到该包中
 const CommonPackage = "type"
 
+// CommonName 项目的公共包名，依赖项目的公共包为<module>_type
+func CommonName(module string) string {
+	if module == "" {
+		return CommonPackage
+	}
+	return module + "_" + CommonPackage
+}
+
+// BuiltinTypes 内置基本类型
+var BuiltinTypes = []string{"int", "float", "bool", "string", "datetime", "objectid"}
+
+// Kind 字段类型的修饰
 type Kind uint32
 
 const (
+	// KindNormal 必填字段
 	KindNormal Kind = iota
+	// KindArray 集合字段，类型后缀[]
 	KindArray
+	// KindOptional 可空字段，类型后缀?
 	KindOptional
+	// KindPrimaryKey 主键，字段名后缀!
 	KindPrimaryKey
 )
 
@@ -20,11 +37,64 @@ var kindName = [...]string{
 	KindPrimaryKey: "KindPrimaryKey",
 }
 
+func (k Kind) String() string {
+	return kindName[k]
+}
+
+// IndexOrder 索引键的排序方式
+type IndexOrder uint32
+
+const (
+	IndexAscending IndexOrder = iota
+	IndexDescending
+	IndexText
+	IndexHashed
+)
+
+// IndexKey 索引键
+type IndexKey struct {
+	// Field yaml字段路径，以'.'分隔
+	Field string
+	// Key 文档中的存储路径，主键映射为_id
+	Key   string
+	Order IndexOrder
+}
+
+// Index 索引
+type Index struct {
+	Name   string
+	Keys   []*IndexKey
+	Unique bool
+	// ExpireAfter TTL秒数，nil表示非TTL索引
+	ExpireAfter *int32
+}
+
+// ShardKey 分片键
+type ShardKey struct {
+	Keys []*IndexKey
+}
+
+// Collection 集合元数据
+type Collection struct {
+	// Name 集合名，默认为表名
+	Name string
+	// Database 数据库名，为空时由调用方决定
+	Database string
+	Indexes  []*Index
+	ShardKey *ShardKey
+}
+
+// Type 字段类型
 type Type struct {
-	Raw     string
-	Name    string
-	Kind    Kind
+	// Raw yaml中的类型名，不含修饰
+	Raw string
+	// Name 目标语言类型名，由链接器填充
+	Name string
+	Kind Kind
+	// Package 目标语言类型所在的包，由链接器填充，同包类型为空
 	Package string
+	// Pos 类型引用位置，内联定义的类型为定义位置
+	Pos Position
 }
 
 func (t *Type) String() string {
@@ -43,10 +113,47 @@ func (t *Type) String() string {
 	return builder.String()
 }
 
+// Deprecation 废弃标记
+type Deprecation struct {
+	// Reason 废弃原因
+	Reason string
+	// Replacement 替代者
+	Replacement string
+}
+
+func (d *Deprecation) String() string {
+	var builder strings.Builder
+	builder.WriteString(d.Reason)
+	if d.Replacement != "" {
+		if d.Reason != "" {
+			builder.WriteString(", ")
+		}
+		builder.WriteString("使用")
+		builder.WriteString(d.Replacement)
+		builder.WriteString("替代")
+	}
+	return builder.String()
+}
+
+// Field 结构字段
 type Field struct {
-	Name    string
+	// Name yaml字段名，即序列化名称
+	Name string
+	// Comment 行尾注释
 	Comment string
 	Type    *Type
+	// Default 默认值，为原始yaml标量，nil表示未设置
+	Default *string
+	// Deprecated 废弃标记，nil表示未废弃
+	Deprecated *Deprecation
+	// Aliases 字段曾用名，解码时兼容
+	Aliases []string
+	// Suppress 注释指令抑制的lint规则
+	Suppress []string
+	// Ident 目标语言标识符，由链接器填充
+	Ident string
+	// Pos 字段名位置
+	Pos Position
 }
 
 func (f *Field) String() string {
@@ -59,10 +166,20 @@ func (f *Field) String() string {
 	return builder.String()
 }
 
+// Structure 结构定义
 type Structure struct {
-	Name    string
+	Name string
+	// Comment 定义前的注释
 	Comment string
 	Fields  []*Field
+	// Deprecated 废弃标记，nil表示未废弃
+	Deprecated *Deprecation
+	// Suppress 注释指令抑制的lint规则
+	Suppress []string
+	// Ident 目标语言标识符，由链接器填充
+	Ident string
+	// Pos 定义位置
+	Pos Position
 }
 
 func (s *Structure) String() string {
@@ -82,9 +199,19 @@ func (s *Structure) String() string {
 	return builder.String()
 }
 
+// EnumField 枚举值，按定义顺序从0开始编号
 type EnumField struct {
-	Name    string
+	Name string
+	// Comment 行尾注释
 	Comment string
+	// Deprecated 废弃标记，nil表示未废弃
+	Deprecated *Deprecation
+	// Suppress 注释指令抑制的lint规则
+	Suppress []string
+	// Ident 目标语言标识符，由链接器填充
+	Ident string
+	// Pos 定义位置
+	Pos Position
 }
 
 func (f *EnumField) String() string {
@@ -95,10 +222,20 @@ func (f *EnumField) String() string {
 	return builder.String()
 }
 
+// Enum 枚举定义
 type Enum struct {
-	Name       string
+	Name string
+	// Comment 定义前的注释
 	Comment    string
 	EnumFields []*EnumField
+	// Deprecated 废弃标记，nil表示未废弃
+	Deprecated *Deprecation
+	// Suppress 注释指令抑制的lint规则
+	Suppress []string
+	// Ident 目标语言标识符，由链接器填充
+	Ident string
+	// Pos 定义位置
+	Pos Position
 }
 
 func (e *Enum) String() string {
@@ -118,11 +255,29 @@ func (e *Enum) String() string {
 	return builder.String()
 }
 
+// Package 包，每个含表的模型对应一个包，不含表的模型合并为公共包
 type Package struct {
-	Name         string
-	Enums        []*Enum
-	Structures   []*Structure
+	// Name 包名，即表名
+	Name       string
+	Enums      []*Enum
+	Structures []*Structure
+	// Dependencies 引用的目标语言包，由链接器填充并排序
 	Dependencies []string
+	// Collection 集合元数据，仅表所在的包非nil
+	Collection *Collection
+	// Module 所属的依赖项目，本项目的包为空；依赖项目的包只供引用，默认不生成
+	Module string
+}
+
+// Own 本项目的包，即需要生成的包
+func Own(packages []*Package) []*Package {
+	result := make([]*Package, 0, len(packages))
+	for _, pack := range packages {
+		if pack.Module == "" {
+			result = append(result, pack)
+		}
+	}
+	return result
 }
 
 func (p *Package) String() string {
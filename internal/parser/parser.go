@@ -1,71 +1,136 @@
 package parser
 
 import (
+	"context"
 	"fmt"
+	"io/fs"
 	"net/url"
-	"os"
-	"path"
+	"runtime"
 	"sort"
 	"strings"
+	"sync"
 
 	"gopkg.in/yaml.v3"
 )
 
+const (
+	// VersionV1 紧凑字段格式
+	VersionV1 = "v1"
+	// VersionV2 支持长格式字段定义
+	VersionV2 = "v2"
+)
+
 // windranger windranger结构
 type windranger struct {
-	Version   string   `yaml:"version"`
-	Kind      string   `yaml:"kind"`
-	Resources []string `yaml:"resources"`
+	Version      string        `yaml:"version"`
+	Kind         string        `yaml:"kind"`
+	Resources    []*Resource   `yaml:"resources"`
+	Dependencies []*Dependency `yaml:"dependencies"`
 }
 
 // model model结构
 type model struct {
-	Version  string `yaml:"version"`
-	Kind     string `yaml:"kind"`
-	Metadata struct {
-		Name string `yaml:"name"`
-	} `yaml:"metadata"`
-	Spec yaml.Node `yaml:"spec"`
+	Version  string    `yaml:"version"`
+	Kind     string    `yaml:"kind"`
+	Metadata metadata  `yaml:"metadata"`
+	Spec     yaml.Node `yaml:"spec"`
 }
 
 // findConflict 查找lst中重复的id
 func findConflict[T any](lst []T, idFunc func(item T) string) []string {
-	set := make(map[string]struct{})
 	conflict := make([]string, 0)
+	for _, item := range findConflictItems(lst, idFunc) {
+		conflict = append(conflict, idFunc(item))
+	}
+	return conflict
+}
+
+// findConflictItems 查找lst中id重复的元素，返回重复出现的元素
+func findConflictItems[T any](lst []T, idFunc func(item T) string) []T {
+	set := make(map[string]struct{})
+	conflict := make([]T, 0)
 	for i := range lst {
 		id := idFunc(lst[i])
 		if _, ok := set[id]; ok {
-			conflict = append(conflict, id)
+			conflict = append(conflict, lst[i])
 		}
 		set[id] = struct{}{}
 	}
 	return conflict
 }
 
+// Cache 文档解析结果缓存，实现方按文件名和内容的哈希存取
+//
+// 包在链接时会被修改，Store应立即序列化，Load每次调用必须返回新的副本
+type Cache interface {
+	Load(name string, content []byte) (*Package, bool)
+	Store(name string, content []byte, pack *Package)
+}
+
 type parser struct {
 	contents [][]byte
-	errors   []error
-	// 当前块内信息
+	// names contents对应的资源文件路径
+	names []string
+	// modules contents所属的依赖项目，本项目为空
+	modules []string
+	// module 正在添加的依赖项目
+	module  string
+	errors  []error
+	cache   Cache
+	workers int
+}
+
+// document 单个yaml块的解析状态，各块相互独立，可以并发解析
+type document struct {
+	file       string
+	version    string
 	tableName  string
 	table      *Structure
 	structures []*Structure
 	enums      []*Enum
+	errors     []error
+	// fatal 块无法解析，之后的块不再参与链接
+	fatal bool
 }
 
 func NewParser() *parser {
 	return &parser{
 		contents: make([][]byte, 0),
+		names:    make([]string, 0),
 		errors:   make([]error, 0),
+		workers:  runtime.GOMAXPROCS(0),
 	}
 }
 
 // AddYaml 添加yaml
 func (p *parser) AddYaml(yaml []byte) *parser {
+	return p.AddYamlFile("", yaml)
+}
+
+// AddYamlFile 添加yaml，name为资源文件路径，用于定位错误
+func (p *parser) AddYamlFile(name string, yaml []byte) *parser {
 	p.contents = append(p.contents, yaml)
+	p.names = append(p.names, name)
+	p.modules = append(p.modules, p.module)
+	return p
+}
+
+// SetWorkers 设置并发解析的块数，默认为GOMAXPROCS，小于1时按1处理
+func (p *parser) SetWorkers(workers int) *parser {
+	if workers < 1 {
+		workers = 1
+	}
+	p.workers = workers
 	return p
 }
 
-// AddYamlPath 添加yaml文件
+// SetCache 设置文档解析结果缓存
+func (p *parser) SetCache(cache Cache) *parser {
+	p.cache = cache
+	return p
+}
+
+// AddYamlPath 添加配置目录中windranger.yaml列出的yaml文件及依赖项目的yaml文件，每个文件可以包含多个yaml块
 func (p *parser) AddYamlPath(uri string) *parser {
 	// 如果path为url，克隆目录
 	root := uri
@@ -85,40 +150,42 @@ func (p *parser) AddYamlPath(uri string) *parser {
 		// }
 		// p.contents = append(p.contents, content)
 	}
-	// 解析配置文件
-	content, err := os.ReadFile(path.Join(root, "windranger.yaml"))
-	if err != nil {
-		p.errors = append(p.errors, err)
-		return p
-	}
-	var cfg windranger
-	err = yaml.Unmarshal(content, &cfg)
-	if err != nil {
-		p.errors = append(p.errors, err)
-		return p
-	}
-	if cfg.Version != "v1" {
-		p.errors = append(p.errors, fmt.Errorf("未知版本号: %s", cfg.Version))
-		return p
-	}
-	if cfg.Kind != "Windranger" {
-		p.errors = append(p.errors, fmt.Errorf("未知资源类型: %s", cfg.Kind))
-		return p
-	}
-	for _, sub := range cfg.Resources {
-		content, err := os.ReadFile(path.Join(root, sub))
-		if err != nil {
-			p.errors = append(p.errors, err)
-			return p
+	return p.addProject(nil, root)
+}
+
+// AddYamlFS 与AddYamlPath相同，从fsys中的dir目录读取，可以使用embed.FS、zip.Reader等
+func (p *parser) AddYamlFS(fsys fs.FS, dir string) *parser {
+	return p.addProject(fsys, dir)
+}
+
+// Directive 抑制lint规则的注释指令，例如: # 名称 windranger:ignore naming
+const Directive = "windranger:ignore"
+
+// trimComment 格式化注释，去除注释指令
+func trimComment(comment string) string {
+	lines := strings.Split(comment, "\n")
+	for i, line := range lines {
+		if index := strings.Index(line, Directive); index >= 0 {
+			lines[i] = line[:index]
 		}
-		p.contents = append(p.contents, content)
 	}
-	return p
+	comment = strings.Join(lines, "\n")
+	return strings.Join(strings.Fields(strings.Trim(comment, "# \t\n")), " ")
 }
 
-// trimComment 格式化注释
-func trimComment(comment string) string {
-	return strings.Join(strings.Fields(strings.Trim(comment, "#")), " ")
+// parseSuppress 解析注释指令中被抑制的规则
+func parseSuppress(comments ...string) []string {
+	var rules []string
+	for _, comment := range comments {
+		for _, line := range strings.Split(comment, "\n") {
+			if index := strings.Index(line, Directive); index >= 0 {
+				rules = append(rules, strings.FieldsFunc(line[index+len(Directive):], func(r rune) bool {
+					return r == ',' || r == ' ' || r == '\t'
+				})...)
+			}
+		}
+	}
+	return rules
 }
 
 // parseComment 格式化首个非空注释
@@ -132,129 +199,159 @@ func parseComment(comments ...string) string {
 }
 
 // parseSequence 解析枚举类型
-func (p *parser) parseSequence(node *yaml.Node) []*EnumField {
+func (d *document) parseSequence(node *yaml.Node) []*EnumField {
 	fields := make([]*EnumField, 0, len(node.Content))
 	for _, enum := range node.Content {
+		if d.version == VersionV2 && enum.Kind == yaml.MappingNode {
+			if field := d.parseLongFormEnumField(enum); field != nil {
+				fields = append(fields, field)
+			}
+			continue
+		}
 		if enum.Kind != yaml.ScalarNode {
-			p.errors = append(p.errors, fmt.Errorf("枚举类型必须为标量"))
+			d.errorf(enum, "枚举类型必须为标量")
 			continue
 		}
 		fields = append(fields, &EnumField{
-			Name:    enum.Value,
-			Comment: parseComment(enum.HeadComment, enum.LineComment),
+			Name:     enum.Value,
+			Comment:  parseComment(enum.HeadComment, enum.LineComment),
+			Suppress: parseSuppress(enum.HeadComment, enum.LineComment),
+			Pos:      d.pos(enum),
 		})
 	}
-	for _, id := range findConflict(fields, func(field *EnumField) string {
+	for _, field := range findConflictItems(fields, func(field *EnumField) string {
 		return field.Name
 	}) {
-		p.errors = append(p.errors, fmt.Errorf("重复的枚举值: %v", id))
+		d.errorAt(field.Pos, "重复的枚举值: %v", field.Name)
 	}
 	return fields
 }
 
+// parseName 解析字段名及其标记
+func parseName(name string) (string, Kind) {
+	switch {
+	case strings.HasSuffix(name, "[]"):
+		return name[:len(name)-2], KindArray
+	case strings.HasSuffix(name, "?"):
+		return name[:len(name)-1], KindOptional
+	case strings.HasSuffix(name, "!"):
+		return name[:len(name)-1], KindPrimaryKey
+	}
+	return name, KindNormal
+}
+
+// parseValue 解析值类型，填充字段类型和注释
+func (d *document) parseValue(field *Field, key *yaml.Node, value *yaml.Node) bool {
+	name := field.Name
+	switch value.Kind {
+	case yaml.SequenceNode:
+		subFields := d.parseSequence(value)
+		enum := &Enum{
+			Name:       name,
+			Comment:    parseComment(key.HeadComment, key.LineComment, value.LineComment),
+			EnumFields: subFields,
+			Suppress:   parseSuppress(key.HeadComment, key.LineComment, value.LineComment),
+			Pos:        d.pos(key),
+		}
+		d.enums = append(d.enums, enum)
+		field.Type.Raw = enum.Name
+		field.Type.Pos = enum.Pos
+		field.Comment = enum.Comment
+	case yaml.MappingNode:
+		subFields := d.parseMapping(value)
+		structure := &Structure{
+			Name:     name,
+			Comment:  parseComment(key.HeadComment, key.LineComment),
+			Fields:   subFields,
+			Suppress: parseSuppress(key.HeadComment, key.LineComment),
+			Pos:      d.pos(key),
+		}
+		if name == d.tableName {
+			d.table = structure
+		}
+		d.structures = append(d.structures, structure)
+		field.Type.Raw = name
+		field.Type.Pos = structure.Pos
+		field.Comment = parseComment(key.HeadComment, structure.Comment)
+	case yaml.ScalarNode:
+		field.Type.Raw = value.Value
+		field.Type.Pos = d.pos(value)
+		field.Comment = parseComment(key.HeadComment, value.LineComment)
+	default:
+		return false
+	}
+	field.Suppress = parseSuppress(key.HeadComment, key.LineComment, value.LineComment)
+	return true
+}
+
 // parseMapping 解析字典类型
-func (p *parser) parseMapping(node *yaml.Node) []*Field {
+func (d *document) parseMapping(node *yaml.Node) []*Field {
 	fields := make([]*Field, 0, len(node.Content)>>1)
 	// 遍历kv-pair
 	var key, value *yaml.Node
 	for i := 0; i < len(node.Content)>>1; i++ {
 		key, value = node.Content[i<<1], node.Content[i<<1|1]
-		name := key.Value
-		kind := KindNormal
-		switch {
-		case strings.HasSuffix(name, "[]"):
-			kind = KindArray
-			name = name[:len(name)-2]
-		case strings.HasSuffix(name, "?"):
-			kind = KindOptional
-			name = name[:len(name)-1]
-		case strings.HasSuffix(name, "!"):
-			kind = KindPrimaryKey
-			name = name[:len(name)-1]
-		}
+		name, kind := parseName(key.Value)
 		field := &Field{
 			Name: name,
 			Type: &Type{
 				Kind: kind,
 			},
+			Pos: d.pos(key),
 		}
 		// 解析值类型
-		switch value.Kind {
-		case yaml.SequenceNode:
-			subFields := p.parseSequence(value)
-			if name == "type" {
-				name = name + ""
-			}
-			enum := &Enum{
-				Name:       name,
-				Comment:    parseComment(key.HeadComment, key.LineComment, value.LineComment),
-				EnumFields: subFields,
-			}
-			p.enums = append(p.enums, enum)
-			// 添加字段
-			field.Type.Raw = enum.Name
-			field.Comment = enum.Comment
-			fields = append(fields, field)
-		case yaml.MappingNode:
-			subFields := p.parseMapping(value)
-			structure := &Structure{
-				Name:    name,
-				Comment: parseComment(key.HeadComment, key.LineComment),
-				Fields:  subFields,
-			}
-			if name == p.tableName {
-				p.table = structure
-			}
-			p.structures = append(p.structures, structure)
-			// 添加字段
-			field.Type.Raw = name
-			field.Comment = parseComment(key.HeadComment, structure.Comment)
-			fields = append(fields, field)
-		case yaml.ScalarNode:
-			// 添加字段
-			field.Type.Raw = value.Value
-			field.Comment = parseComment(key.HeadComment, value.LineComment)
+		var ok bool
+		if d.version == VersionV2 && IsLongForm(value) {
+			ok = d.parseLongForm(field, key, value)
+		} else {
+			ok = d.parseValue(field, key, value)
+		}
+		if ok {
 			fields = append(fields, field)
 		}
 	}
-	for _, id := range findConflict(fields, func(field *Field) string {
+	for _, field := range findConflictItems(fields, func(field *Field) string {
 		return field.Name
 	}) {
-		p.errors = append(p.errors, fmt.Errorf("重复的字段名: %v", id))
+		d.errorAt(field.Pos, "重复的字段名: %v", field.Name)
 	}
+	d.checkAliases(fields)
 	return fields
 }
 
 // parseDoc 解析yaml块
-func (p *parser) parseDoc(node *yaml.Node) *Package {
+func (d *document) parseDoc(node *yaml.Node, meta *metadata) *Package {
 	// 准备块内缓存
-	p.table = nil
-	p.enums = make([]*Enum, 0)
-	p.structures = make([]*Structure, 0)
-	p.parseMapping(node)
+	d.table = nil
+	d.enums = make([]*Enum, 0)
+	d.structures = make([]*Structure, 0)
+	d.parseMapping(node)
 	pack := &Package{
 		Name:         CommonPackage,
-		Enums:        p.enums,
-		Structures:   p.structures,
+		Enums:        d.enums,
+		Structures:   d.structures,
 		Dependencies: make([]string, 0),
 	}
-	if p.table != nil {
-		pack.Name = p.table.Name
+	if d.table != nil {
+		pack.Name = d.table.Name
+		pack.Collection = d.parseCollection(meta)
+	} else if meta.hasCollection() {
+		d.errors = append(d.errors, fmt.Errorf("集合元数据缺少对应的表: %s", meta.Name))
 	}
 	return pack
 }
 
 // normalize 标准化包结构
 func (p *parser) normalize(pack *Package) {
-	for _, id := range findConflict(pack.Enums, func(enum *Enum) string {
+	for _, enum := range findConflictItems(pack.Enums, func(enum *Enum) string {
 		return enum.Name
 	}) {
-		p.errors = append(p.errors, fmt.Errorf("重复的枚举类型: %v", id))
+		p.errors = append(p.errors, errorAt(enum.Pos, "重复的枚举类型: %v", enum.Name))
 	}
-	for _, id := range findConflict(pack.Structures, func(structure *Structure) string {
+	for _, structure := range findConflictItems(pack.Structures, func(structure *Structure) string {
 		return structure.Name
 	}) {
-		p.errors = append(p.errors, fmt.Errorf("重复的结构: %v", id))
+		p.errors = append(p.errors, errorAt(structure.Pos, "重复的结构: %v", structure.Name))
 	}
 	sort.SliceStable(pack.Enums, func(i, j int) bool {
 		return pack.Enums[i].Name < pack.Enums[j].Name
@@ -266,33 +363,41 @@ func (p *parser) normalize(pack *Package) {
 
 // link 链接yaml块，构建输出
 func (p *parser) link(packages []*Package) ([]*Package, []error) {
-	common := &Package{
-		Name:         CommonPackage,
-		Enums:        make([]*Enum, 0),
-		Structures:   make([]*Structure, 0),
-		Dependencies: make([]string, 0),
-	}
+	// 每个项目各自合并公共包
+	commons := make(map[string]*Package)
+	modules := make([]string, 0)
 	linked := make([]*Package, 0)
-	var hasCommon bool
 	for _, pack := range packages {
 		if pack.Name == CommonPackage {
+			common, ok := commons[pack.Module]
+			if !ok {
+				common = &Package{
+					Name:         CommonName(pack.Module),
+					Module:       pack.Module,
+					Enums:        make([]*Enum, 0),
+					Structures:   make([]*Structure, 0),
+					Dependencies: make([]string, 0),
+				}
+				commons[pack.Module] = common
+				modules = append(modules, pack.Module)
+			}
 			common.Enums = append(common.Enums, pack.Enums...)
 			common.Structures = append(common.Structures, pack.Structures...)
-			hasCommon = true
 		} else {
 			linked = append(linked, pack)
 			p.normalize(pack)
 		}
 	}
-	if hasCommon {
-		linked = append(linked, common)
-		p.normalize(common)
+	for _, module := range modules {
+		linked = append(linked, commons[module])
+		p.normalize(commons[module])
 	}
 	for _, id := range findConflict(linked, func(pack *Package) string {
 		return pack.Name
 	}) {
 		p.errors = append(p.errors, fmt.Errorf("重复的包: %v", id))
 	}
+	p.validateCollections(linked)
 	sort.SliceStable(linked, func(i, j int) bool {
 		return linked[i].Name < linked[j].Name
 	})
@@ -302,33 +407,94 @@ func (p *parser) link(packages []*Package) ([]*Package, []error) {
 	return linked, nil
 }
 
+// parseContent 解析第i个yaml块
+func (p *parser) parseContent(i int) (*Package, *document) {
+	d := &document{
+		file: p.names[i],
+	}
+	content := p.contents[i]
+	if p.cache != nil {
+		if pack, ok := p.cache.Load(d.file, content); ok {
+			pack.Module = p.modules[i]
+			return pack, d
+		}
+	}
+	var cfg model
+	if err := yaml.Unmarshal(content, &cfg); err != nil {
+		d.errors = append(d.errors, d.located(err))
+		d.fatal = true
+		return nil, d
+	}
+	if cfg.Version != VersionV1 && cfg.Version != VersionV2 {
+		d.errors = append(d.errors, d.located(fmt.Errorf("未知版本号: %s", cfg.Version)))
+		d.fatal = true
+		return nil, d
+	}
+	if cfg.Kind != "Model" {
+		d.errors = append(d.errors, d.located(fmt.Errorf("未知资源类型: %s", cfg.Kind)))
+		d.fatal = true
+		return nil, d
+	}
+	d.version = cfg.Version
+	d.tableName = cfg.Metadata.Name
+	pack := d.parseDoc(&cfg.Spec, &cfg.Metadata)
+	pack.Module = p.modules[i]
+	for j := range d.errors {
+		d.errors[j] = d.located(d.errors[j])
+	}
+	// 只缓存没有错误的文档
+	if p.cache != nil && len(d.errors) == 0 {
+		p.cache.Store(d.file, content, pack)
+	}
+	return pack, d
+}
+
 // Parse 解析生成Info结构
+//
+// 各yaml块并发解析，结果和错误按添加顺序合并，与顺序解析相同
 func (p *parser) Parse() ([]*Package, []error) {
+	return p.ParseContext(context.Background())
+}
+
+// ParseContext 与Parse相同，ctx结束后不再解析新的yaml块并返回ctx.Err()
+func (p *parser) ParseContext(ctx context.Context) ([]*Package, []error) {
 	// 提前返回
 	if len(p.errors) != 0 {
 		return nil, p.errors
 	}
-	var (
-		err error
-	)
-	packages := make([]*Package, 0)
-	for _, content := range p.contents {
-		var cfg model
-		err = yaml.Unmarshal(content, &cfg)
-		if err != nil {
-			p.errors = append(p.errors, err)
-			break
-		}
-		if cfg.Version != "v1" {
-			p.errors = append(p.errors, fmt.Errorf("未知版本号: %s", cfg.Version))
-			break
+	results := make([]*Package, len(p.contents))
+	documents := make([]*document, len(p.contents))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < p.workers && w < len(p.contents); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i], documents[i] = p.parseContent(i)
+			}
+		}()
+	}
+dispatch:
+	for i := range p.contents {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break dispatch
 		}
-		if cfg.Kind != "Model" {
-			p.errors = append(p.errors, fmt.Errorf("未知资源类型: %s", cfg.Kind))
+	}
+	close(jobs)
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, []error{err}
+	}
+	packages := make([]*Package, 0, len(results))
+	for i, d := range documents {
+		p.errors = append(p.errors, d.errors...)
+		if d.fatal {
 			break
 		}
-		p.tableName = cfg.Metadata.Name
-		packages = append(packages, p.parseDoc(&cfg.Spec))
+		packages = append(packages, results[i])
 	}
 	return p.link(packages)
 }
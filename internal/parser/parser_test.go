@@ -394,4 +394,311 @@ func TestAddYamlPath(t *testing.T) {
 		_, err := parser.Parse()
 		assert.Nil(t, err)
 	}
-}
\ No newline at end of file
+}
+func TestLongForm(t *testing.T) {
+	parser := NewParser()
+	parser.AddYaml([]byte(
+		`version: v2
+kind: Model
+metadata:
+  name: demo
+spec:
+  # 示例
+  demo:
+    id!: string # 主键
+    name:
+      type: string
+      optional: true
+      description: 名称
+      default: unknown
+      deprecated: true
+    tags:
+      type: string
+      array: true
+    # 状态
+    status:
+      type: [on, off]
+      default: on
+    meta: !struct
+      type: string # 类型
+`))
+	packages, err := parser.Parse()
+	assert.Nil(t, err)
+	assert.Equal(t,
+		`[package demo
+#状态
+type status enum {
+	on#
+	off#
+}
+#示例
+type demo struct {
+	id [KindPrimaryKey](string)#主键
+	name [KindOptional](string)#名称
+	tags [KindArray](string)#
+	status [KindNormal](status)#状态
+	meta [KindNormal](meta)#
+}
+#
+type meta struct {
+	type [KindNormal](string)#类型
+}]`, fmt.Sprintf("%v", packages))
+	demo := packages[0].Structures[0]
+	assert.Equal(t, "unknown", *demo.Fields[1].Default)
+	assert.Equal(t, &Deprecation{}, demo.Fields[1].Deprecated)
+	assert.Nil(t, demo.Fields[2].Default)
+	assert.Equal(t, "on", *demo.Fields[3].Default)
+}
+
+func TestLongFormV1(t *testing.T) {
+	parser := NewParser()
+	parser.AddYaml([]byte(
+		`version: v1
+kind: Model
+spec:
+  demo:
+    name:
+      type: string
+      optional: bool
+`))
+	packages, err := parser.Parse()
+	assert.Nil(t, err)
+	assert.Equal(t,
+		`[package type
+#
+type demo struct {
+	name [KindNormal](name)#
+}
+#
+type name struct {
+	type [KindNormal](string)#
+	optional [KindNormal](bool)#
+}]`, fmt.Sprintf("%v", packages))
+}
+
+func TestLongFormFault(t *testing.T) {
+	parser := NewParser()
+	parser.AddYaml([]byte(
+		`version: v2
+kind: Model
+spec:
+  demo:
+    name?:
+      type: string
+      array: true
+    flag:
+      type: bool
+      optional: maybe
+`))
+	_, err := parser.Parse()
+	assert.Equal(t, []error{
+		&Error{Pos: Position{Line: 5, Column: 5}, Err: fmt.Errorf("字段name的属性冲突: KindOptional, KindArray")},
+		&Error{Pos: Position{Line: 10, Column: 17}, Err: fmt.Errorf("字段flag的属性optional必须为布尔值")},
+	}, err)
+}
+
+func TestDeprecation(t *testing.T) {
+	parser := NewParser()
+	parser.AddYaml([]byte(
+		`version: v2
+kind: Model
+spec:
+  demo:
+    name:
+      type: string
+      deprecated: 拆分为姓和名
+    nick:
+      type: string
+      deprecated: {reason: 不再使用, replacement: name}
+    author:
+      type:
+        name: string
+      deprecated: false
+    state:
+      type:
+        - on
+        - off: {description: 关闭, deprecated: true}
+      deprecated: true
+`))
+	packages, err := parser.Parse()
+	assert.Nil(t, err)
+	pack := packages[0]
+	demo := pack.Structures[1]
+	assert.Equal(t, &Deprecation{Reason: "拆分为姓和名"}, demo.Fields[0].Deprecated)
+	assert.Equal(t, &Deprecation{Reason: "不再使用", Replacement: "name"}, demo.Fields[1].Deprecated)
+	assert.Equal(t, "不再使用, 使用name替代", demo.Fields[1].Deprecated.String())
+	assert.Nil(t, demo.Fields[2].Deprecated)
+	assert.Nil(t, pack.Structures[0].Deprecated)
+	assert.Equal(t, &Deprecation{}, pack.Enums[0].Deprecated)
+	assert.Nil(t, pack.Enums[0].EnumFields[0].Deprecated)
+	assert.Equal(t, "关闭", pack.Enums[0].EnumFields[1].Comment)
+	assert.Equal(t, &Deprecation{}, pack.Enums[0].EnumFields[1].Deprecated)
+}
+
+func TestCollection(t *testing.T) {
+	parser := NewParser()
+	parser.AddYaml([]byte(
+		`version: v1
+kind: Model
+metadata:
+  name: demo
+  collection: demos
+  database: app
+  indexes:
+    - keys: [name, -author.name]
+      unique: true
+    - keys: [id]
+      name: by_id
+  text: [name]
+  ttl:
+    field: update_at
+    expire_after: 60
+  shard_key:
+    keys: [author.name]
+    hashed: true
+spec:
+  demo:
+    id!: string
+    name: string
+    update_at: datetime
+    author:
+      name: string
+`))
+	packages, err := parser.Parse()
+	assert.Nil(t, err)
+	collection := packages[0].Collection
+	assert.Equal(t, "demos", collection.Name)
+	assert.Equal(t, "app", collection.Database)
+	assert.Len(t, collection.Indexes, 4)
+	assert.Equal(t, "name_1_author.name_-1", collection.Indexes[0].Name)
+	assert.True(t, collection.Indexes[0].Unique)
+	assert.Equal(t, "by_id", collection.Indexes[1].Name)
+	assert.Equal(t, "_id", collection.Indexes[1].Keys[0].Key)
+	assert.Equal(t, IndexText, collection.Indexes[2].Keys[0].Order)
+	assert.Equal(t, int32(60), *collection.Indexes[3].ExpireAfter)
+	assert.Equal(t, &IndexKey{Field: "author.name", Key: "author.name", Order: IndexHashed}, collection.ShardKey.Keys[0])
+}
+
+func TestCollectionDefault(t *testing.T) {
+	parser := NewParser()
+	parser.AddYaml([]byte(
+		`version: v1
+kind: Model
+metadata:
+  name: demo
+spec:
+  demo:
+    id!: string
+`))
+	packages, err := parser.Parse()
+	assert.Nil(t, err)
+	assert.Equal(t, &Collection{Name: "demo", Indexes: []*Index{}}, packages[0].Collection)
+}
+
+func TestCollectionFault(t *testing.T) {
+	parser := NewParser()
+	parser.AddYaml([]byte(
+		`version: v1
+kind: Model
+metadata:
+  name: demo
+  indexes:
+    - keys: [missing]
+    - keys: [author.name.first]
+  ttl:
+    field: name
+spec:
+  demo:
+    name: string
+    author:
+      name: string
+`))
+	_, err := parser.Parse()
+	assert.Equal(t, []error{
+		fmt.Errorf("字段不存在: demo.missing"),
+		fmt.Errorf("字段不是结构: demo.author.name"),
+		fmt.Errorf("TTL字段必须为datetime: demo.name"),
+	}, err)
+}
+
+func TestAliases(t *testing.T) {
+	{
+		parser := NewParser()
+		parser.AddYaml([]byte(
+			`version: v2
+kind: Model
+spec:
+  demo:
+    heading:
+      type: string
+      was: title
+      aliases: [caption]
+`))
+		packages, err := parser.Parse()
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"title", "caption"}, packages[0].Structures[0].Fields[0].Aliases)
+	}
+	{
+		parser := NewParser()
+		parser.AddYaml([]byte(
+			`version: v2
+kind: Model
+spec:
+  demo:
+    name: string
+    heading:
+      type: string
+      was: name
+`))
+		_, err := parser.Parse()
+		assert.Equal(t, []error{&Error{Pos: Position{Line: 6, Column: 5}, Err: fmt.Errorf("曾用名冲突: name")}}, err)
+	}
+}
+
+func TestPosition(t *testing.T) {
+	parser := NewParser()
+	parser.AddYamlFile("demo.yaml", []byte(
+		`version: v1
+kind: Model
+spec:
+  demo:
+    name: string
+    author:
+      name: string
+    name: int
+`))
+	_, err := parser.Parse()
+	assert.Equal(t, []error{
+		&Error{Pos: Position{File: "demo.yaml", Line: 8, Column: 5}, Err: fmt.Errorf("重复的字段名: name")},
+	}, err)
+	assert.Equal(t, "demo.yaml:8:5: 重复的字段名: name", err[0].Error())
+
+	parser = NewParser()
+	parser.AddYamlFile("demo.yaml", []byte(
+		`version: v1
+kind: Model
+spec:
+  demo:
+    name: string
+    author:
+      name: string
+    sex: [male]
+`))
+	packages, err := parser.Parse()
+	assert.Nil(t, err)
+	demo, author := packages[0].Structures[1], packages[0].Structures[0]
+	assert.Equal(t, Position{File: "demo.yaml", Line: 4, Column: 3}, demo.Pos)
+	assert.Equal(t, Position{File: "demo.yaml", Line: 5, Column: 5}, demo.Fields[0].Pos)
+	assert.Equal(t, Position{File: "demo.yaml", Line: 5, Column: 11}, demo.Fields[0].Type.Pos)
+	assert.Equal(t, Position{File: "demo.yaml", Line: 6, Column: 5}, author.Pos)
+	assert.Equal(t, author.Pos, demo.Fields[1].Type.Pos)
+	assert.Equal(t, Position{File: "demo.yaml", Line: 8, Column: 11}, packages[0].Enums[0].EnumFields[0].Pos)
+
+	parser = NewParser()
+	parser.AddYamlFile("bad.yaml", []byte("version: v1\nkind: [\n"))
+	_, err = parser.Parse()
+	var e *Error
+	assert.ErrorAs(t, err[0], &e)
+	assert.Equal(t, "bad.yaml", e.Pos.File)
+	assert.True(t, e.Pos.IsValid())
+}
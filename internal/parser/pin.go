@@ -0,0 +1,180 @@
+package parser
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io/fs"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// RemoteDir 远程资源下载到配置目录中的位置
+const RemoteDir = ".windranger/resources"
+
+// Resource windranger.yaml中的一条资源声明，可以只写路径或URL
+type Resource struct {
+	// Path 文件、glob模式或子目录，相对于配置目录
+	Path string `yaml:"path,omitempty"`
+	// URL http(s)远程资源，下载到RemoteDir后读取
+	URL string `yaml:"url,omitempty"`
+	// SHA256 固定的文件内容哈希，只能用于单个文件
+	SHA256 string `yaml:"sha256,omitempty"`
+}
+
+// Pin 固定了sha256或来自远程的资源文件
+type Pin struct {
+	// Profile 声明资源的windranger.yaml
+	Profile string
+	// Entry 资源声明中的路径或URL
+	Entry string
+	// Name 读取的文件路径，远程资源为下载位置
+	Name string
+	// URL 远程资源地址，本地资源为空
+	URL string
+	// SHA256 期望的哈希，为空表示未固定
+	SHA256 string
+}
+
+// sha256Hex 合法的sha256
+var sha256Hex = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// remoteBase 可以保留在下载文件名中的URL文件名
+var remoteBase = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+// isURL 判断资源是否为远程资源
+func isURL(resource string) bool {
+	return strings.HasPrefix(resource, "http://") || strings.HasPrefix(resource, "https://")
+}
+
+// UnmarshalYAML 支持字符串和映射两种写法
+func (r *Resource) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		if isURL(node.Value) {
+			r.URL = node.Value
+		} else {
+			r.Path = node.Value
+		}
+		return nil
+	}
+	type plain Resource
+	if err := node.Decode((*plain)(r)); err != nil {
+		return err
+	}
+	switch {
+	case (r.Path == "") == (r.URL == ""):
+		return fmt.Errorf("资源必须且只能指定path或url: 第%d行", node.Line)
+	case r.URL != "" && !isURL(r.URL):
+		return fmt.Errorf("远程资源只支持http和https: %s", r.URL)
+	case r.SHA256 != "" && !sha256Hex.MatchString(r.SHA256):
+		return fmt.Errorf("sha256必须为64位小写十六进制: %s", r.SHA256)
+	}
+	return nil
+}
+
+// Entry 资源声明中的路径或URL
+func (r *Resource) Entry() string {
+	if r.URL != "" {
+		return r.URL
+	}
+	return r.Path
+}
+
+// remoteName 远程资源下载后的文件名，使用URL的哈希避免冲突
+func remoteName(rawURL string) string {
+	sum := sha256.Sum256([]byte(rawURL))
+	base := "resource.yaml"
+	if u, err := url.Parse(rawURL); err == nil && remoteBase.MatchString(path.Base(u.Path)) {
+		base = path.Base(u.Path)
+	}
+	return hex.EncodeToString(sum[:6]) + "-" + base
+}
+
+// Sum 文件内容的sha256
+func Sum(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// Mismatch 哈希与windranger.yaml中固定的值不一致时的错误
+func Mismatch(subject string, want string, got string) error {
+	return fmt.Errorf("%s的sha256不一致: 期望%s，实际%s，确认变更后执行windranger deps update --update-hashes", subject, want, got)
+}
+
+// Verify 校验资源内容，未固定时总是通过
+func (p *Pin) Verify(content []byte) error {
+	if p == nil || p.SHA256 == "" {
+		return nil
+	}
+	if got := Sum(content); got != p.SHA256 {
+		return Mismatch(p.Entry, p.SHA256, got)
+	}
+	return nil
+}
+
+// Pins 解析配置目录及嵌套配置，返回固定了sha256或来自远程的资源
+func Pins(root string) ([]*Pin, error) {
+	r := &resolver{}
+	if _, err := r.resolve(root); err != nil {
+		return nil, err
+	}
+	return r.pins, nil
+}
+
+// digest 项目内容的哈希，由各资源文件相对于配置目录的路径及内容哈希按顺序组成
+type digest struct {
+	hash.Hash
+	root string
+}
+
+func newDigest(root string) *digest {
+	return &digest{Hash: sha256.New(), root: path.Clean(root)}
+}
+
+// add 加入一个资源文件
+func (d *digest) add(name string, content []byte) {
+	name = strings.TrimPrefix(name, d.root+"/")
+	fmt.Fprintf(d, "%s %s\n", name, Sum(content))
+}
+
+// String 十六进制哈希
+func (d *digest) String() string {
+	return hex.EncodeToString(d.Sum(nil))
+}
+
+// Digest 配置目录中全部资源的哈希，用于固定依赖项目的sha256
+func Digest(root string) (string, error) {
+	r := &resolver{}
+	resources, err := r.resolve(root)
+	if err != nil {
+		return "", err
+	}
+	d := newDigest(root)
+	for _, resource := range resources {
+		content, err := r.read(resource)
+		if err != nil {
+			return "", err
+		}
+		d.add(resource, content)
+	}
+	return d.String(), nil
+}
+
+// read 读取资源文件，远程资源尚未下载时给出提示
+func (r *resolver) read(name string) ([]byte, error) {
+	content, err := r.readFile(name)
+	pin := r.pinned[name]
+	if err != nil {
+		if pin != nil && pin.URL != "" && errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("远程资源%s尚未下载，请执行windranger deps update", pin.URL)
+		}
+		return nil, err
+	}
+	return content, nil
+}
@@ -0,0 +1,78 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPins(t *testing.T) {
+	validator := require.New(t)
+	common := []byte("version: v1\nkind: Model\nmetadata:\n  name: common\nspec:\n  # 名称\n  name: string\n")
+	sum := Sum(common)
+	remote := "https://example.com/schema/common.yaml"
+	fsys := fstest.MapFS{
+		"app/windranger.yaml": {Data: []byte(`version: v1
+kind: Windranger
+resources:
+  - path: common.yaml
+    sha256: ` + sum + `
+  - url: ` + remote + `
+    sha256: ` + sum + `
+dependencies:
+  - name: base
+    path: ../base
+    sha256: ` + strings.Repeat("0", 64) + `
+`)},
+		"app/common.yaml":      {Data: common},
+		"base/windranger.yaml": {Data: []byte("version: v1\nkind: Windranger\nresources:\n  - common.yaml\n")},
+		"base/common.yaml":     {Data: common},
+	}
+	r := &resolver{fsys: fsys}
+	_, err := r.resolve("app")
+	validator.Nil(err)
+	validator.Len(r.pins, 2)
+	validator.Equal("app/.windranger/resources/"+remoteName(remote), r.pins[1].Name)
+	validator.True(strings.HasSuffix(r.pins[1].Name, "-common.yaml"))
+	validator.Equal("app/windranger.yaml", r.pins[1].Profile)
+
+	_, errs := NewParser().AddYamlFS(fsys, "app").Parse()
+	validator.Len(errs, 1)
+	validator.EqualError(errs[0], "远程资源"+remote+"尚未下载，请执行windranger deps update")
+
+	// 下载的内容被修改
+	fsys[r.pins[1].Name] = &fstest.MapFile{Data: append(append([]byte{}, common...), '\n')}
+	_, errs = NewParser().AddYamlFS(fsys, "app").Parse()
+	validator.Len(errs, 1)
+	validator.EqualError(errs[0], Mismatch(remote, sum, Sum(fsys[r.pins[1].Name].Data)).Error())
+
+	// 依赖内容与固定的哈希不一致
+	fsys[r.pins[1].Name] = &fstest.MapFile{Data: common}
+	_, errs = NewParser().AddYamlFS(fsys, "app").Parse()
+	validator.Len(errs, 1)
+	digest := newDigest("base")
+	digest.add("base/common.yaml", common)
+	validator.EqualError(errs[0], Mismatch("依赖base", strings.Repeat("0", 64), digest.String()).Error())
+}
+
+func TestPinsFault(t *testing.T) {
+	validator := require.New(t)
+	for _, c := range []struct {
+		resources string
+		err       string
+	}{
+		{"  - sha256: " + strings.Repeat("0", 64) + "\n", "资源必须且只能指定path或url: 第4行"},
+		{"  - url: ftp://example.com/common.yaml\n", "远程资源只支持http和https: ftp://example.com/common.yaml"},
+		{"  - path: common.yaml\n    sha256: ABC\n", "sha256必须为64位小写十六进制: ABC"},
+		{"  - path: \"*.yaml\"\n    sha256: " + strings.Repeat("0", 64) + "\n", "只能为单个文件固定sha256: *.yaml"},
+	} {
+		fsys := fstest.MapFS{
+			"windranger.yaml": {Data: []byte("version: v1\nkind: Windranger\nresources:\n" + c.resources)},
+			"common.yaml":     {Data: []byte("version: v1\n")},
+		}
+		_, err := ResourcesFS(fsys, ".")
+		validator.EqualError(err, c.err)
+	}
+}
@@ -0,0 +1,104 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Position 源码位置，行列从1开始，Line为0表示仅知道所在文件
+type Position struct {
+	// File 资源文件路径，通过AddYaml添加的内容为空
+	File   string
+	Line   int
+	Column int
+}
+
+// IsValid 是否包含行列信息
+func (p Position) IsValid() bool {
+	return p.Line > 0
+}
+
+func (p Position) String() string {
+	if !p.IsValid() {
+		return p.File
+	}
+	if p.File == "" {
+		return fmt.Sprintf("%d:%d", p.Line, p.Column)
+	}
+	return fmt.Sprintf("%s:%d:%d", p.File, p.Line, p.Column)
+}
+
+// Error 带源码位置的错误
+//
+// 未关联资源文件时错误信息与原始错误相同
+type Error struct {
+	Pos Position
+	Err error
+}
+
+func (e *Error) Error() string {
+	if e.Pos.File == "" {
+		return e.Err.Error()
+	}
+	return e.Pos.String() + ": " + e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// pos 返回节点在当前文档中的位置
+func (d *document) pos(node *yaml.Node) Position {
+	return Position{
+		File:   d.file,
+		Line:   node.Line,
+		Column: node.Column,
+	}
+}
+
+// errorf 记录节点处的错误
+func (d *document) errorf(node *yaml.Node, format string, args ...any) {
+	d.errors = append(d.errors, &Error{
+		Pos: d.pos(node),
+		Err: fmt.Errorf(format, args...),
+	})
+}
+
+// errorAt 构造定义位置处的错误
+func errorAt(pos Position, format string, args ...any) error {
+	return &Error{
+		Pos: pos,
+		Err: fmt.Errorf(format, args...),
+	}
+}
+
+// errorAt 记录定义位置处的错误
+func (d *document) errorAt(pos Position, format string, args ...any) {
+	d.errors = append(d.errors, errorAt(pos, format, args...))
+}
+
+// yamlLine 匹配yaml错误信息中的行号
+var yamlLine = regexp.MustCompile(`line (\d+)`)
+
+// located 为缺少位置的错误补充当前文件，yaml语法错误同时补充行号
+func (d *document) located(err error) error {
+	var e *Error
+	if errors.As(err, &e) {
+		return err
+	}
+	pos := Position{
+		File: d.file,
+	}
+	if match := yamlLine.FindStringSubmatch(err.Error()); match != nil {
+		pos.Line, _ = strconv.Atoi(match[1])
+		pos.Column = 1
+	}
+	return &Error{
+		Pos: pos,
+		Err: err,
+	}
+}
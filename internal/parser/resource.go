@@ -0,0 +1,290 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Profile 配置文件名
+const Profile = "windranger.yaml"
+
+// Resources 解析配置目录中的windranger.yaml，返回资源文件路径
+//
+// 资源可以是文件、glob模式（支持'**'匹配任意层目录）或包含windranger.yaml的子目录；
+// 结果按资源声明顺序展开，模式匹配的文件按路径排序，重复的文件只保留第一次出现
+func Resources(root string) ([]string, error) {
+	return (&resolver{}).resolve(root)
+}
+
+// ResourcesFS 与Resources相同，从fsys中的dir目录读取，返回fsys中的路径
+//
+// 资源路径相对于dir解析，不能超出fsys的根目录
+func ResourcesFS(fsys fs.FS, dir string) ([]string, error) {
+	return (&resolver{fsys: fsys}).resolve(dir)
+}
+
+// resolver 展开windranger.yaml中的资源
+type resolver struct {
+	// fsys 为nil时使用操作系统文件系统
+	fsys fs.FS
+	// stack 正在展开的配置目录，用于检测循环引用
+	stack []string
+	seen  map[string]struct{}
+	files []string
+	// pins 固定了sha256或来自远程的资源，pinned按文件路径索引
+	pins   []*Pin
+	pinned map[string]*Pin
+}
+
+func (r *resolver) readFile(name string) ([]byte, error) {
+	if r.fsys == nil {
+		return os.ReadFile(name)
+	}
+	return fs.ReadFile(r.fsys, name)
+}
+
+func (r *resolver) stat(name string) (fs.FileInfo, error) {
+	if r.fsys == nil {
+		return os.Stat(name)
+	}
+	return fs.Stat(r.fsys, name)
+}
+
+func (r *resolver) readDir(name string) ([]fs.DirEntry, error) {
+	if r.fsys == nil {
+		return os.ReadDir(name)
+	}
+	return fs.ReadDir(r.fsys, name)
+}
+
+// resolve 展开root中的配置，返回全部资源文件
+func (r *resolver) resolve(root string) ([]string, error) {
+	r.seen = make(map[string]struct{})
+	r.pinned = make(map[string]*Pin)
+	if err := r.profile(path.Clean(root)); err != nil {
+		return nil, err
+	}
+	return r.files, nil
+}
+
+// isProfile 判断目录是否包含windranger.yaml
+func (r *resolver) isProfile(dir string) bool {
+	info, err := r.stat(path.Join(dir, Profile))
+	return err == nil && !info.IsDir()
+}
+
+// profile 展开dir中的windranger.yaml
+func (r *resolver) profile(dir string) error {
+	for i, parent := range r.stack {
+		if parent == dir {
+			cycle := append(append([]string{}, r.stack[i:]...), dir)
+			return fmt.Errorf("windranger.yaml循环引用: %s", strings.Join(cycle, " -> "))
+		}
+	}
+	content, err := r.readFile(path.Join(dir, Profile))
+	if err != nil {
+		// 根配置直接返回，调用方据此判断配置是否存在
+		if len(r.stack) == 0 {
+			return err
+		}
+		return fmt.Errorf("%s: %w", dir, err)
+	}
+	var cfg windranger
+	if err := yaml.Unmarshal(content, &cfg); err != nil {
+		return err
+	}
+	if cfg.Version != VersionV1 {
+		return fmt.Errorf("未知版本号: %s", cfg.Version)
+	}
+	if cfg.Kind != "Windranger" {
+		return fmt.Errorf("未知资源类型: %s", cfg.Kind)
+	}
+	r.stack = append(r.stack, dir)
+	defer func() {
+		r.stack = r.stack[:len(r.stack)-1]
+	}()
+	for _, resource := range cfg.Resources {
+		if err := r.resource(dir, resource); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resource 展开一条资源声明
+func (r *resolver) resource(dir string, resource *Resource) error {
+	if resource.URL != "" {
+		name := path.Join(dir, RemoteDir, remoteName(resource.URL))
+		r.pin(dir, resource, name)
+		return nil
+	}
+	name := path.Join(dir, resource.Path)
+	if r.fsys != nil && !fs.ValidPath(name) {
+		return fmt.Errorf("资源路径超出根目录: %s", name)
+	}
+	if !isPattern(resource.Path) {
+		if info, err := r.stat(name); err == nil && info.IsDir() {
+			if resource.SHA256 != "" {
+				return fmt.Errorf("只能为单个文件固定sha256: %s", name)
+			}
+			if !r.isProfile(name) {
+				return fmt.Errorf("资源目录缺少%s: %s", Profile, name)
+			}
+			return r.profile(name)
+		}
+		// 不存在的文件在读取时报告
+		r.pin(dir, resource, name)
+		return nil
+	}
+	if resource.SHA256 != "" {
+		return fmt.Errorf("只能为单个文件固定sha256: %s", name)
+	}
+	matches, err := r.glob(name)
+	if err != nil {
+		return err
+	}
+	if len(matches) == 0 {
+		return fmt.Errorf("资源模式没有匹配的文件: %s", name)
+	}
+	for _, match := range matches {
+		if r.isProfile(match) {
+			if err := r.profile(match); err != nil {
+				return err
+			}
+			continue
+		}
+		r.add(match)
+	}
+	return nil
+}
+
+// add 添加资源文件，忽略重复的文件
+func (r *resolver) add(name string) {
+	if _, ok := r.seen[name]; ok {
+		return
+	}
+	r.seen[name] = struct{}{}
+	r.files = append(r.files, name)
+}
+
+// pin 添加单个文件资源，固定了sha256或来自远程时记录
+func (r *resolver) pin(dir string, resource *Resource, name string) {
+	if _, ok := r.seen[name]; !ok && (resource.URL != "" || resource.SHA256 != "") {
+		pin := &Pin{
+			Profile: path.Join(dir, Profile),
+			Entry:   resource.Entry(),
+			Name:    name,
+			URL:     resource.URL,
+			SHA256:  resource.SHA256,
+		}
+		r.pins = append(r.pins, pin)
+		r.pinned[name] = pin
+	}
+	r.add(name)
+}
+
+// isPattern 判断资源是否为glob模式
+func isPattern(resource string) bool {
+	return strings.ContainsAny(resource, "*?[")
+}
+
+// glob 展开模式，只匹配模型文件和包含windranger.yaml的目录，结果按路径排序
+func (r *resolver) glob(pattern string) ([]string, error) {
+	// 模式之前的目录
+	var base []string
+	segments := strings.Split(pattern, "/")
+	for len(segments) > 0 && !isPattern(segments[0]) {
+		base = append(base, segments[0])
+		segments = segments[1:]
+	}
+	root := strings.Join(base, "/")
+	if root == "" && strings.HasPrefix(pattern, "/") {
+		root = "/"
+	}
+	if root == "" {
+		root = "."
+	}
+	if _, err := r.stat(root); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	found := make(map[string]struct{})
+	if err := r.walk(root, segments, found); err != nil {
+		return nil, err
+	}
+	matches := make([]string, 0, len(found))
+	for name := range found {
+		matches = append(matches, name)
+	}
+	sort.Strings(matches)
+	return matches, nil
+}
+
+// walk 在dir中匹配剩余的模式片段，'**'匹配零或多层目录，遇到包含windranger.yaml的目录时不再深入而是将其作为结果，通配符不匹配'.'开头的名称
+func (r *resolver) walk(dir string, segments []string, found map[string]struct{}) error {
+	if len(segments) == 0 {
+		return nil
+	}
+	segment, rest := segments[0], segments[1:]
+	if segment == "**" {
+		if err := r.walk(dir, rest, found); err != nil {
+			return err
+		}
+	}
+	entries, err := r.readDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if strings.HasPrefix(name, ".") && !strings.HasPrefix(segment, ".") {
+			continue
+		}
+		full := path.Join(dir, name)
+		if segment == "**" {
+			if !entry.IsDir() {
+				continue
+			}
+			// 嵌套配置作为匹配结果，由调用方按其自身的windranger.yaml展开
+			if r.isProfile(full) {
+				found[full] = struct{}{}
+				continue
+			}
+			if err := r.walk(full, segments, found); err != nil {
+				return err
+			}
+			continue
+		}
+		ok, err := path.Match(segment, name)
+		if err != nil {
+			return fmt.Errorf("资源模式无效: %s", segment)
+		}
+		if !ok {
+			continue
+		}
+		switch {
+		case len(rest) != 0:
+			if entry.IsDir() {
+				if err := r.walk(full, rest, found); err != nil {
+					return err
+				}
+			}
+		case entry.IsDir():
+			if r.isProfile(full) {
+				found[full] = struct{}{}
+			}
+		case name != Profile:
+			found[full] = struct{}{}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package parser
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+)
+
+func profile(resources ...string) *fstest.MapFile {
+	content := "version: v1\nkind: Windranger\nresources:\n"
+	for _, resource := range resources {
+		content += fmt.Sprintf("  - %q\n", resource)
+	}
+	return &fstest.MapFile{Data: []byte(content)}
+}
+
+func TestResources(t *testing.T) {
+	validator := require.New(t)
+	model := &fstest.MapFile{Data: []byte("version: v1\n")}
+	fsys := fstest.MapFS{
+		"model/windranger.yaml":            profile("common.yaml", "models/**/*.yaml", "*.yaml", "nested", "shared/*"),
+		"model/common.yaml":                model,
+		"model/b.yaml":                     model,
+		"model/models/z.yaml":              model,
+		"model/models/a/y.yaml":            model,
+		"model/models/a/b/x.yaml":          model,
+		"model/models/a/b/x.txt":           model,
+		"model/models/.hidden/w.yaml":      model,
+		"model/models/sub/windranger.yaml": profile("v.yaml"),
+		"model/models/sub/v.yaml":          model,
+		"model/nested/windranger.yaml":     profile("u.yaml", "../common.yaml"),
+		"model/nested/u.yaml":              model,
+		"model/shared/windranger.yaml":     profile("t.yaml"),
+		"model/shared/t.yaml":              model,
+	}
+	expected := []string{
+		"model/common.yaml",
+		"model/models/a/b/x.yaml",
+		"model/models/a/y.yaml",
+		// '**'遇到的嵌套配置按其自身的windranger.yaml展开
+		"model/models/sub/v.yaml",
+		"model/models/z.yaml",
+		"model/b.yaml",
+		"model/nested/u.yaml",
+		"model/shared/t.yaml",
+	}
+	for i := 0; i < 3; i++ {
+		resources, err := ResourcesFS(fsys, "model")
+		validator.Nil(err)
+		validator.Equal(expected, resources)
+	}
+
+	// 操作系统文件系统的结果相同
+	root := t.TempDir()
+	for name, file := range fsys {
+		validator.Nil(os.MkdirAll(filepath.Join(root, filepath.Dir(name)), os.ModePerm))
+		validator.Nil(os.WriteFile(filepath.Join(root, name), file.Data, os.ModePerm))
+	}
+	resources, err := Resources(root + "/model")
+	validator.Nil(err)
+	for i, name := range expected {
+		validator.Equal(root+"/"+name, resources[i])
+	}
+}
+
+func TestResourcesFault(t *testing.T) {
+	validator := require.New(t)
+	fsys := fstest.MapFS{
+		"a/windranger.yaml":   profile("../b"),
+		"b/windranger.yaml":   profile("../c/*"),
+		"c/a/windranger.yaml": profile("../../a"),
+		"d/windranger.yaml":   profile("missing/*.yaml"),
+		"e/windranger.yaml":   profile("dir"),
+		"e/dir/x.yaml":        &fstest.MapFile{Data: []byte("version: v1\n")},
+		"f/windranger.yaml":   profile("[.yaml"),
+	}
+	_, err := ResourcesFS(fsys, "a")
+	validator.EqualError(err, "windranger.yaml循环引用: a -> b -> c/a -> a")
+	_, err = ResourcesFS(fsys, "d")
+	validator.EqualError(err, "资源模式没有匹配的文件: d/missing/*.yaml")
+	_, err = ResourcesFS(fsys, "e")
+	validator.EqualError(err, "资源目录缺少windranger.yaml: e/dir")
+	_, err = ResourcesFS(fsys, "f")
+	validator.EqualError(err, "资源模式无效: [.yaml")
+	_, err = ResourcesFS(fsys, "missing")
+	validator.ErrorIs(err, os.ErrNotExist)
+}
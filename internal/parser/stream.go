@@ -0,0 +1,78 @@
+package parser
+
+import (
+	"bytes"
+	"strings"
+)
+
+// AddYamlStream 添加包含多个yaml块的内容，块之间以'---'分隔，name为资源文件路径
+//
+// 各块在前面补齐空行，错误位置为块在整个内容中的行号
+func (p *parser) AddYamlStream(name string, content []byte) *parser {
+	documents := splitDocuments(content)
+	if len(documents) == 0 {
+		// 保留原内容以报告错误
+		return p.AddYamlFile(name, content)
+	}
+	for _, document := range documents {
+		p.AddYamlFile(name, document)
+	}
+	return p
+}
+
+// isSeparator 判断行是否为yaml块的开始或结束标记，标记后只允许注释
+func isSeparator(line string) bool {
+	line = strings.TrimRight(line, "\r\n")
+	for _, marker := range []string{"---", "..."} {
+		if !strings.HasPrefix(line, marker) {
+			continue
+		}
+		rest := line[len(marker):]
+		if rest == "" {
+			return true
+		}
+		if rest[0] != ' ' && rest[0] != '\t' {
+			return false
+		}
+		rest = strings.TrimSpace(rest)
+		return rest == "" || rest[0] == '#'
+	}
+	return false
+}
+
+// isBlank 判断yaml块是否只包含空行和注释
+func isBlank(document string) bool {
+	for _, line := range strings.Split(document, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "#") {
+			return false
+		}
+	}
+	return true
+}
+
+// splitDocuments 按分隔行拆分yaml块，忽略空块，每块以空行补齐到原始行号
+func splitDocuments(content []byte) [][]byte {
+	var (
+		documents [][]byte
+		current   strings.Builder
+		// start 当前块第一行之前的行数
+		start int
+	)
+	flush := func() {
+		if !isBlank(current.String()) {
+			documents = append(documents, append(bytes.Repeat([]byte{'\n'}, start), current.String()...))
+		}
+		current.Reset()
+	}
+	for i, line := range strings.SplitAfter(string(content), "\n") {
+		if isSeparator(line) {
+			flush()
+			start = i + 1
+			continue
+		}
+		current.WriteString(line)
+	}
+	flush()
+	return documents
+}
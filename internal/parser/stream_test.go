@@ -0,0 +1,60 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitDocuments(t *testing.T) {
+	validator := require.New(t)
+	documents := splitDocuments([]byte("# 头部注释\n---\na: 1\n--- # 第二个\nb: |\n  ---x\n...\n\n---\n# 空块\n---\nc: 3"))
+	validator.Equal([]string{
+		"\n\na: 1\n",
+		"\n\n\n\nb: |\n  ---x\n",
+		"\n\n\n\n\n\n\n\n\n\n\nc: 3",
+	}, func() []string {
+		result := make([]string, len(documents))
+		for i, document := range documents {
+			result[i] = string(document)
+		}
+		return result
+	}())
+	validator.Empty(splitDocuments([]byte("\n---\n# 注释\n")))
+	validator.False(isSeparator("---x\n"))
+	validator.False(isSeparator("--- a: 1\n"))
+	validator.True(isSeparator("...\r\n"))
+}
+
+func TestAddYamlStream(t *testing.T) {
+	validator := require.New(t)
+	valid := `version: v1
+kind: Model
+metadata:
+  name: author
+spec:
+  # 作者
+  author:
+    name: string # 姓名
+---
+version: v1
+kind: Model
+metadata:
+  name: demo
+spec:
+  # 示例
+  demo:
+    id!: objectid # 主键
+    author: author # 作者
+`
+	packages, errs := NewParser().AddYamlStream("<stdin>", []byte(valid)).Parse()
+	validator.Nil(errs)
+	validator.Len(packages, 2)
+	validator.Equal(Position{File: "<stdin>", Line: 16, Column: 3}, packages[1].Structures[0].Pos)
+
+	// yaml语法错误的行号为整个内容中的行号
+	broken := valid + "---\nversion: v1\nkind: Model\nmetadata:\n  name: broken\nspec:\n  broken: [\n"
+	_, errs = NewParser().AddYamlStream("<stdin>", []byte(broken)).Parse()
+	validator.Len(errs, 1)
+	validator.Equal(Position{File: "<stdin>", Line: 25, Column: 1}, errs[0].(*Error).Pos)
+}
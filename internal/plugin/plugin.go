@@ -0,0 +1,158 @@
+package plugin
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/wzyjerry/windranger/internal/ir"
+	"github.com/wzyjerry/windranger/internal/lint"
+	"github.com/wzyjerry/windranger/internal/output"
+	"github.com/wzyjerry/windranger/internal/parser"
+	"github.com/wzyjerry/windranger/internal/region"
+)
+
+// Version 插件协议版本
+const Version = "v1"
+
+// Prefix 插件可执行文件名前缀，--plugin=foo查找windranger-gen-foo
+const Prefix = "windranger-gen-"
+
+// Request 通过标准输入发送给插件的请求
+type Request struct {
+	Version string `json:"version"`
+	// Parameters 命令行传入的插件参数
+	Parameters map[string]string `json:"parameters"`
+	// IR 链接后的中间表示
+	IR *ir.Document `json:"ir"`
+}
+
+// File 插件生成的文件
+type File struct {
+	// Name 相对于输出目录的路径，使用'/'分隔
+	Name    string `json:"name"`
+	Content string `json:"content"`
+}
+
+// Diagnostic 插件报告的诊断信息
+type Diagnostic struct {
+	// Level warning或error
+	Level   string       `json:"level"`
+	Message string       `json:"message"`
+	Pos     *ir.Position `json:"pos,omitempty"`
+}
+
+// Response 插件通过标准输出返回的响应
+type Response struct {
+	Files       []*File       `json:"files"`
+	Diagnostics []*Diagnostic `json:"diagnostics"`
+}
+
+// Lookup 查找插件可执行文件
+//
+// name包含路径分隔符时视为路径直接使用；否则在PATH中查找Prefix+name，
+// name已带Prefix时按原名查找。不会按裸名查找，避免运行同名的无关程序
+func Lookup(name string) (string, error) {
+	if strings.ContainsRune(name, '/') || strings.ContainsRune(name, filepath.Separator) {
+		return name, nil
+	}
+	if !strings.HasPrefix(name, Prefix) {
+		name = Prefix + name
+	}
+	path, err := exec.LookPath(name)
+	if err != nil {
+		return "", fmt.Errorf("找不到插件: %s", name)
+	}
+	return path, nil
+}
+
+// Run 运行插件，插件的标准错误输出透传到stderr
+func Run(name string, packages []*parser.Package, linker string, parameters map[string]string) (*Response, error) {
+	path, err := Lookup(name)
+	if err != nil {
+		return nil, err
+	}
+	if parameters == nil {
+		parameters = make(map[string]string)
+	}
+	request, err := json.Marshal(&Request{
+		Version:    Version,
+		Parameters: parameters,
+		IR:         ir.NewDocument(packages, linker),
+	})
+	if err != nil {
+		return nil, err
+	}
+	var stdout bytes.Buffer
+	cmd := exec.Command(path)
+	cmd.Stdin = bytes.NewReader(request)
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("插件%s执行失败: %w", name, err)
+	}
+	response := new(Response)
+	if err := json.Unmarshal(stdout.Bytes(), response); err != nil {
+		return nil, fmt.Errorf("插件%s的响应无效: %w", name, err)
+	}
+	return response, nil
+}
+
+// Report 将插件的诊断信息转换为检查结果，规则名为插件名
+func (r *Response) Report(name string) ([]*lint.Diagnostic, error) {
+	diagnostics := make([]*lint.Diagnostic, len(r.Diagnostics))
+	for i, d := range r.Diagnostics {
+		diagnostic := &lint.Diagnostic{
+			Rule:    name,
+			Message: d.Message,
+		}
+		switch d.Level {
+		case "warning":
+			diagnostic.Level = lint.LevelWarning
+		case "error":
+			diagnostic.Level = lint.LevelError
+		default:
+			return nil, fmt.Errorf("插件%s的诊断级别无效: %s", name, d.Level)
+		}
+		if d.Pos != nil {
+			diagnostic.Pos = parser.Position{
+				File:   d.Pos.File,
+				Line:   d.Pos.Line,
+				Column: d.Pos.Column,
+			}
+		}
+		diagnostics[i] = diagnostic
+	}
+	return diagnostics, nil
+}
+
+// Render 将插件生成的文件放入输出目录，保留已有文件中的自定义区域，文件路径不能超出输出目录
+func (r *Response) Render(out string) (*output.Set, error) {
+	set := output.NewSet()
+	for _, file := range r.Files {
+		name := filepath.Clean(filepath.FromSlash(file.Name))
+		if file.Name == "" || filepath.IsAbs(name) || name == ".." || strings.HasPrefix(name, ".."+string(filepath.Separator)) {
+			return nil, fmt.Errorf("插件生成的文件路径无效: %s", file.Name)
+		}
+		filename := filepath.Join(out, name)
+		content, err := region.Preserve(filename, []byte(file.Content))
+		if err != nil {
+			return nil, err
+		}
+		set.Add(filename, content)
+	}
+	return set, nil
+}
+
+// Write 将插件生成的文件写入输出目录
+func (r *Response) Write(out string) error {
+	set, err := r.Render(out)
+	if err != nil {
+		return err
+	}
+	return set.Write()
+}
@@ -0,0 +1,108 @@
+package plugin
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/wzyjerry/windranger/internal/generator/gogo"
+	"github.com/wzyjerry/windranger/internal/lint"
+	"github.com/wzyjerry/windranger/internal/parser"
+)
+
+// script 在临时目录中创建插件脚本，脚本将请求保存到request.json
+func script(t *testing.T, name string, response string) string {
+	dir := t.TempDir()
+	path := filepath.Join(dir, name)
+	content := "#!/bin/sh\ncat > " + filepath.Join(dir, "request.json") + "\ncat <<'EOF'\n" + response + "\nEOF\n"
+	require.Nil(t, os.WriteFile(path, []byte(content), 0755))
+	return path
+}
+
+func packages(t *testing.T) []*parser.Package {
+	packages, errs := parser.NewParser().AddYaml([]byte(`version: v1
+kind: Model
+metadata:
+  name: demo
+spec:
+  # 示例
+  demo:
+    id!: objectid # 主键
+`)).Parse()
+	require.Nil(t, errs)
+	packages, errs = gogo.Link(packages)
+	require.Nil(t, errs)
+	return packages
+}
+
+func TestRun(t *testing.T) {
+	validator := require.New(t)
+	path := script(t, Prefix+"demo", `{
+  "files": [{"name": "ts/demo.ts", "content": "export interface Demo {}\n"}],
+  "diagnostics": [{"level": "warning", "message": "忽略主键", "pos": {"file": "demo.yaml", "line": 8, "column": 5}}]
+}`)
+	response, err := Run(path, packages(t), "go", map[string]string{"lang": "ts"})
+	validator.Nil(err)
+
+	content, err := os.ReadFile(filepath.Join(filepath.Dir(path), "request.json"))
+	validator.Nil(err)
+	var request Request
+	validator.Nil(json.Unmarshal(content, &request))
+	validator.Equal(Version, request.Version)
+	validator.Equal(map[string]string{"lang": "ts"}, request.Parameters)
+	validator.Equal("linked", request.IR.Stage)
+	restored, err := request.IR.Restore()
+	validator.Nil(err)
+	validator.Equal(packages(t), restored)
+
+	diagnostics, err := response.Report("demo")
+	validator.Nil(err)
+	validator.Equal([]*lint.Diagnostic{{
+		Rule:    "demo",
+		Level:   lint.LevelWarning,
+		Message: "忽略主键",
+		Pos:     parser.Position{File: "demo.yaml", Line: 8, Column: 5},
+	}}, diagnostics)
+
+	out := t.TempDir()
+	validator.Nil(response.Write(out))
+	content, err = os.ReadFile(filepath.Join(out, "ts", "demo.ts"))
+	validator.Nil(err)
+	validator.Equal("export interface Demo {}\n", string(content))
+}
+
+func TestLookup(t *testing.T) {
+	validator := require.New(t)
+	path := script(t, Prefix+"demo", `{}`)
+	t.Setenv("PATH", filepath.Dir(path))
+	found, err := Lookup("demo")
+	validator.Nil(err)
+	validator.Equal(path, found)
+	found, err = Lookup(Prefix + "demo")
+	validator.Nil(err)
+	validator.Equal(path, found)
+	// 不按裸名查找同名的无关程序
+	other := script(t, "other", `{}`)
+	t.Setenv("PATH", filepath.Dir(path)+string(os.PathListSeparator)+filepath.Dir(other))
+	_, err = Lookup("other")
+	validator.EqualError(err, "找不到插件: "+Prefix+"other")
+	_, err = Lookup("missing")
+	validator.EqualError(err, "找不到插件: "+Prefix+"missing")
+	found, err = Lookup("./bin/other")
+	validator.Nil(err)
+	validator.Equal("./bin/other", found)
+}
+
+func TestRunFault(t *testing.T) {
+	validator := require.New(t)
+	_, err := Run(script(t, "broken", `not json`), packages(t), "go", nil)
+	validator.ErrorContains(err, "的响应无效")
+
+	response, err := Run(script(t, "escape", `{"files": [{"name": "../demo.ts", "content": ""}], "diagnostics": [{"level": "fatal", "message": ""}]}`), packages(t), "go", nil)
+	validator.Nil(err)
+	validator.EqualError(response.Write(t.TempDir()), "插件生成的文件路径无效: ../demo.ts")
+	_, err = response.Report("escape")
+	validator.EqualError(err, "插件escape的诊断级别无效: fatal")
+}
@@ -0,0 +1,189 @@
+package region
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+const (
+	// Begin 自定义区域开始标记，后接区域名
+	Begin = "windranger:begin custom "
+	// End 自定义区域结束标记
+	End = "windranger:end"
+)
+
+// Region 自定义区域
+type Region struct {
+	Name string
+	// Body 开始与结束标记之间的原始内容，包含换行
+	Body string
+	// Line 开始标记所在行，从1开始
+	Line int
+}
+
+// OrphanError 已有文件中的自定义区域在新生成的文件中不存在
+type OrphanError struct {
+	File    string
+	Regions []*Region
+}
+
+func (e *OrphanError) Error() string {
+	names := make([]string, len(e.Regions))
+	for i, region := range e.Regions {
+		names[i] = fmt.Sprintf("%s:%d: %s", e.File, region.Line, region.Name)
+	}
+	return "自定义区域失去对应的定义，请迁移或删除后重新生成:\n  " + strings.Join(names, "\n  ")
+}
+
+// marker 解析标记行，返回区域名和是否为开始标记
+func marker(line string) (string, bool, bool) {
+	if i := strings.Index(line, Begin); i != -1 {
+		// 区域名后可能是块注释的结束符
+		fields := strings.Fields(line[i+len(Begin):])
+		if len(fields) == 0 {
+			return "", true, true
+		}
+		return fields[0], true, true
+	}
+	if strings.Contains(line, End) {
+		return "", false, true
+	}
+	return "", false, false
+}
+
+// Extract 提取内容中的全部自定义区域
+//
+// 标记可以使用任意注释语法，例如'// windranger:begin custom X'或'# windranger:end'
+func Extract(content []byte) ([]*Region, error) {
+	var (
+		regions []*Region
+		current *Region
+		body    strings.Builder
+	)
+	names := make(map[string]struct{})
+	lines := strings.SplitAfter(string(content), "\n")
+	for i, line := range lines {
+		name, begin, ok := marker(line)
+		switch {
+		case !ok:
+			if current != nil {
+				body.WriteString(line)
+			}
+		case begin:
+			if current != nil {
+				return nil, fmt.Errorf("第%d行: 自定义区域%s未结束", i+1, current.Name)
+			}
+			if name == "" {
+				return nil, fmt.Errorf("第%d行: 自定义区域缺少名称", i+1)
+			}
+			if _, ok := names[name]; ok {
+				return nil, fmt.Errorf("第%d行: 自定义区域重复: %s", i+1, name)
+			}
+			names[name] = struct{}{}
+			current = &Region{Name: name, Line: i + 1}
+			body.Reset()
+		default:
+			if current == nil {
+				return nil, fmt.Errorf("第%d行: 多余的自定义区域结束标记", i+1)
+			}
+			current.Body = body.String()
+			regions = append(regions, current)
+			current = nil
+		}
+	}
+	if current != nil {
+		return nil, fmt.Errorf("第%d行: 自定义区域%s未结束", current.Line, current.Name)
+	}
+	return regions, nil
+}
+
+// Merge 将已有内容中的自定义区域写回新生成的内容
+//
+// 新内容中不存在且非空的区域作为孤立区域返回，空区域直接丢弃
+func Merge(generated []byte, existing []byte) ([]byte, []*Region, error) {
+	saved, err := Extract(existing)
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, err := Extract(generated); err != nil {
+		return nil, nil, fmt.Errorf("生成内容中的自定义区域无效: %w", err)
+	}
+	bodies := make(map[string]*Region, len(saved))
+	for _, region := range saved {
+		bodies[region.Name] = region
+	}
+	var (
+		result  bytes.Buffer
+		current *Region
+	)
+	for _, line := range strings.SplitAfter(string(generated), "\n") {
+		name, begin, ok := marker(line)
+		switch {
+		case ok && begin:
+			result.WriteString(line)
+			if region, ok := bodies[name]; ok {
+				current = region
+				result.WriteString(region.Body)
+				delete(bodies, name)
+			}
+		case ok:
+			current = nil
+			result.WriteString(line)
+		case current == nil:
+			result.WriteString(line)
+		}
+	}
+	var orphans []*Region
+	for _, region := range bodies {
+		if strings.TrimSpace(region.Body) != "" {
+			orphans = append(orphans, region)
+		}
+	}
+	sort.SliceStable(orphans, func(i, j int) bool {
+		return orphans[i].Line < orphans[j].Line
+	})
+	return result.Bytes(), orphans, nil
+}
+
+// Preserve 保留已有文件中的自定义区域，文件不存在时原样返回生成内容
+//
+// 存在孤立区域时返回*OrphanError，此时不应覆盖已有文件
+func Preserve(filename string, generated []byte) ([]byte, error) {
+	existing, err := os.ReadFile(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return generated, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	merged, orphans, err := Merge(generated, existing)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	if len(orphans) != 0 {
+		return nil, &OrphanError{File: filename, Regions: orphans}
+	}
+	return merged, nil
+}
+
+// Strip 去除全部自定义区域的内容，保留标记行
+func Strip(content []byte) []byte {
+	var (
+		result bytes.Buffer
+		inside bool
+	)
+	for _, line := range strings.SplitAfter(string(content), "\n") {
+		_, begin, ok := marker(line)
+		if ok || !inside {
+			result.WriteString(line)
+		}
+		if ok {
+			inside = begin
+		}
+	}
+	return result.Bytes()
+}
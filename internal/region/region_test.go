@@ -0,0 +1,125 @@
+package region
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const generated = `package model
+
+// windranger:begin custom imports
+// windranger:end
+
+type User struct{}
+
+// windranger:begin custom User
+// windranger:end
+`
+
+func TestExtract(t *testing.T) {
+	validator := require.New(t)
+	regions, err := Extract([]byte(`a
+# windranger:begin custom A
+line 1
+
+line 2
+# windranger:end
+<!-- windranger:begin custom B -->
+<!-- windranger:end -->
+`))
+	validator.Nil(err)
+	validator.Equal([]*Region{
+		{Name: "A", Body: "line 1\n\nline 2\n", Line: 2},
+		{Name: "B", Body: "", Line: 7},
+	}, regions)
+}
+
+func TestExtractFault(t *testing.T) {
+	validator := require.New(t)
+	for content, message := range map[string]string{
+		"// windranger:begin custom A\n":                               "第1行: 自定义区域A未结束",
+		"// windranger:begin custom A\n// windranger:begin custom B\n": "第2行: 自定义区域A未结束",
+		"// windranger:begin custom \n// windranger:end\n":             "第1行: 自定义区域缺少名称",
+		"// windranger:end\n":                                          "第1行: 多余的自定义区域结束标记",
+		"// windranger:begin custom A\n// windranger:end\n" +
+			"// windranger:begin custom A\n// windranger:end\n": "第3行: 自定义区域重复: A",
+	} {
+		_, err := Extract([]byte(content))
+		validator.EqualError(err, message)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	validator := require.New(t)
+	existing := `package model
+
+// windranger:begin custom imports
+import "fmt"
+// windranger:end
+
+type User struct{}
+
+// windranger:begin custom User
+func (User) String() string {
+    return fmt.Sprint("user")
+}
+// windranger:end
+
+// windranger:begin custom Removed
+// windranger:end
+
+// windranger:begin custom Address
+func (Address) Valid() bool { return true }
+// windranger:end
+`
+	merged, orphans, err := Merge([]byte(generated), []byte(existing))
+	validator.Nil(err)
+	validator.Equal(`package model
+
+// windranger:begin custom imports
+import "fmt"
+// windranger:end
+
+type User struct{}
+
+// windranger:begin custom User
+func (User) String() string {
+    return fmt.Sprint("user")
+}
+// windranger:end
+`, string(merged))
+	// 空区域直接丢弃，非空区域为孤立区域
+	validator.Equal([]*Region{{Name: "Address", Body: "func (Address) Valid() bool { return true }\n", Line: 18}}, orphans)
+}
+
+func TestPreserve(t *testing.T) {
+	validator := require.New(t)
+	filename := filepath.Join(t.TempDir(), "model.go")
+	content, err := Preserve(filename, []byte(generated))
+	validator.Nil(err)
+	validator.Equal(generated, string(content))
+
+	validator.Nil(os.WriteFile(filename, []byte("// windranger:begin custom Address\nvar _ = 1\n// windranger:end\n"), os.ModePerm))
+	_, err = Preserve(filename, []byte(generated))
+	validator.Equal(&OrphanError{File: filename, Regions: []*Region{{Name: "Address", Body: "var _ = 1\n", Line: 1}}}, err)
+	validator.EqualError(err, "自定义区域失去对应的定义，请迁移或删除后重新生成:\n  "+filename+":1: Address")
+}
+
+func TestStrip(t *testing.T) {
+	validator := require.New(t)
+	validator.Equal(generated, string(Strip([]byte(`package model
+
+// windranger:begin custom imports
+import "fmt"
+// windranger:end
+
+type User struct{}
+
+// windranger:begin custom User
+func (User) String() string { return fmt.Sprint("user") }
+// windranger:end
+`))))
+}
@@ -0,0 +1,68 @@
+package util
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// IdentPolicy 目标语言标识符策略
+//
+// 策略作用于已完成大小写转换的标识符，仅负责保证其在目标语言中合法，
+// 不影响序列化使用的原始yaml名称
+type IdentPolicy struct {
+	// Keywords 目标语言保留字，命中时追加'_'转义
+	Keywords map[string]struct{}
+	// Transliterate 非ASCII字符转写，为nil或返回false时拒绝该标识符
+	Transliterate func(r rune) (string, bool)
+}
+
+// NewIdentPolicy 根据保留字列表创建标识符策略
+func NewIdentPolicy(keywords ...string) *IdentPolicy {
+	policy := &IdentPolicy{
+		Keywords: make(map[string]struct{}, len(keywords)),
+	}
+	for _, keyword := range keywords {
+		policy.Keywords[keyword] = struct{}{}
+	}
+	return policy
+}
+
+// Sanitize 转写非ASCII字符，转义保留字和数字开头的标识符
+//
+//	type  => type_
+//	2fa   => X2fa
+//	名称   => error
+func (p *IdentPolicy) Sanitize(ident string) (string, error) {
+	var builder strings.Builder
+	for _, r := range ident {
+		if r > unicode.MaxASCII {
+			if p.Transliterate == nil {
+				return "", fmt.Errorf("非ASCII标识符: %s", ident)
+			}
+			s, ok := p.Transliterate(r)
+			if !ok {
+				return "", fmt.Errorf("无法转写的标识符: %s", ident)
+			}
+			builder.WriteString(s)
+			continue
+		}
+		builder.WriteRune(r)
+	}
+	result := builder.String()
+	if result == "" {
+		return "", fmt.Errorf("空标识符: %q", ident)
+	}
+	for _, r := range result {
+		if r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			return "", fmt.Errorf("非法标识符: %s", ident)
+		}
+	}
+	if unicode.IsDigit(rune(result[0])) {
+		result = "X" + result
+	}
+	if _, ok := p.Keywords[result]; ok {
+		result += "_"
+	}
+	return result, nil
+}
@@ -0,0 +1,43 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdentPolicy(t *testing.T) {
+	validator := require.New(t)
+	policy := NewIdentPolicy("type", "func", "range")
+	for in, out := range map[string]string{
+		"type":     "type_",
+		"Type":     "Type",
+		"range":    "range_",
+		"2fa":      "X2fa",
+		"UserInfo": "UserInfo",
+	} {
+		ident, err := policy.Sanitize(in)
+		validator.Nil(err)
+		validator.Equal(out, ident)
+	}
+	for _, in := range []string{"名称", "", "a.b", "a b"} {
+		_, err := policy.Sanitize(in)
+		validator.NotNil(err)
+	}
+}
+
+func TestIdentPolicyTransliterate(t *testing.T) {
+	validator := require.New(t)
+	policy := NewIdentPolicy()
+	policy.Transliterate = func(r rune) (string, bool) {
+		if r == 'é' {
+			return "e", true
+		}
+		return "", false
+	}
+	ident, err := policy.Sanitize("Café")
+	validator.Nil(err)
+	validator.Equal("Cafe", ident)
+	_, err = policy.Sanitize("名称")
+	validator.NotNil(err)
+}
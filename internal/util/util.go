@@ -1,6 +1,7 @@
 package util
 
 import (
+	"strconv"
 	"strings"
 	"text/template"
 	"unicode"
@@ -13,16 +14,26 @@ var (
 	rules    = ruleset()
 	acronyms = make(map[string]struct{})
 	FuncMap  = template.FuncMap{
-		"snake":          Snake,
-		"camel":          Camel,
-		"pascal":         Pascal,
-		"protoPascal":    ProtoPascal,
-		"upper":          strings.ToUpper,
-		"lower":          strings.ToLower,
-		"plural":         Plural,
-		"add":            Add,
-		"getPackageName": GetPackageName,
-		"goType":         GoType,
+		"snake":           Snake,
+		"camel":           Camel,
+		"pascal":          Pascal,
+		"protoPascal":     ProtoPascal,
+		"upper":           strings.ToUpper,
+		"lower":           strings.ToLower,
+		"plural":          Plural,
+		"add":             Add,
+		"getPackageName":  GetPackageName,
+		"goType":          GoType,
+		"tsType":          TsType,
+		"pyType":          PyType,
+		"protoType":       ProtoType,
+		"kebab":           Kebab,
+		"singular":        Singular,
+		"join":            strings.Join,
+		"quote":           strconv.Quote,
+		"goIndexValue":    GoIndexValue,
+		"shardKeyIndexed": ShardKeyIndexed,
+		"hasAliases":      HasAliases,
 	}
 )
 
@@ -77,6 +88,14 @@ func Snake(s string) string {
 	return b.String()
 }
 
+// Kebab converts the given name into a kebab-case.
+//
+//	UserInfo  => user-info
+//	full_name => full-name
+func Kebab(s string) string {
+	return strings.ReplaceAll(Snake(s), "_", "-")
+}
+
 // Camel converts the given name into a camelCase.
 //
 //	user_info  => userInfo
@@ -145,6 +164,23 @@ func Plural(name string) string {
 	return p
 }
 
+// Singular 名称的单数形式
+//
+//	users => user
+func Singular(name string) string {
+	return rules.Singularize(name)
+}
+
+// IsPlural 名称是否为复数形式，不可数名词同时视为单数和复数
+func IsPlural(name string) bool {
+	return rules.Pluralize(name) == name
+}
+
+// IsSingular 名称是否为单数形式，不可数名词同时视为单数和复数
+func IsSingular(name string) bool {
+	return rules.Singularize(rules.Pluralize(name)) == name
+}
+
 // GetPackageName 获取包名
 func GetPackageName(name string) string {
 	return Camel(name)
@@ -177,3 +213,143 @@ func GoType(in parser.Type) string {
 	result += full
 	return result
 }
+
+// builtin 按内置类型映射目标语言类型，非内置类型使用链接后的类型名
+func builtin(in parser.Type, typemap map[string]string) string {
+	if t, ok := typemap[in.Raw]; ok {
+		return t
+	}
+	return in.Name
+}
+
+// TsType TypeScript类型
+//
+//	int      => number
+//	string[] => string[]
+//	address? => Address | null
+func TsType(in parser.Type) string {
+	result := builtin(in, map[string]string{
+		"int":      "number",
+		"float":    "number",
+		"bool":     "boolean",
+		"string":   "string",
+		"datetime": "Date",
+		"objectid": "string",
+	})
+	switch in.Kind {
+	case parser.KindArray:
+		return result + "[]"
+	case parser.KindOptional:
+		return result + " | null"
+	}
+	return result
+}
+
+// PyType Python类型注解
+//
+//	int      => int
+//	string[] => list[str]
+//	address? => Optional[Address]
+func PyType(in parser.Type) string {
+	result := builtin(in, map[string]string{
+		"int":      "int",
+		"float":    "float",
+		"bool":     "bool",
+		"string":   "str",
+		"datetime": "datetime",
+		"objectid": "ObjectId",
+	})
+	switch in.Kind {
+	case parser.KindArray:
+		return "list[" + result + "]"
+	case parser.KindOptional:
+		return "Optional[" + result + "]"
+	}
+	return result
+}
+
+// ProtoType protobuf字段类型
+//
+//	int      => int64
+//	string[] => repeated string
+//	address? => optional Address
+func ProtoType(in parser.Type) string {
+	result := builtin(in, map[string]string{
+		"int":      "int64",
+		"float":    "double",
+		"bool":     "bool",
+		"string":   "string",
+		"datetime": "google.protobuf.Timestamp",
+		"objectid": "string",
+	})
+	switch in.Kind {
+	case parser.KindArray:
+		return "repeated " + result
+	case parser.KindOptional:
+		return "optional " + result
+	}
+	return result
+}
+
+// GoIndexValue 索引键在mongo-driver中的取值
+func GoIndexValue(order parser.IndexOrder) string {
+	switch order {
+	case parser.IndexDescending:
+		return "-1"
+	case parser.IndexText:
+		return `"text"`
+	case parser.IndexHashed:
+		return `"hashed"`
+	}
+	return "1"
+}
+
+// ShardKeyIndexed 声明的索引是否以分片键为前缀，此时分片无需再单独创建索引
+//
+// 键模式相同的索引不能以不同的名称或选项重复创建
+func ShardKeyIndexed(collection *parser.Collection) bool {
+	if collection == nil || collection.ShardKey == nil {
+		return false
+	}
+	keys := collection.ShardKey.Keys
+	for _, index := range collection.Indexes {
+		if len(index.Keys) < len(keys) {
+			continue
+		}
+		covered := true
+		for i, key := range keys {
+			if index.Keys[i].Key != key.Key || index.Keys[i].Order != key.Order {
+				covered = false
+				break
+			}
+		}
+		if covered {
+			return true
+		}
+	}
+	return false
+}
+
+// HasAliases 结构中是否有字段声明了曾用名
+func HasAliases(structure *parser.Structure) bool {
+	for _, field := range structure.Fields {
+		if len(field.Aliases) != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Unique 去除重复元素，保留首次出现的顺序
+func Unique(values []string) []string {
+	set := make(map[string]struct{}, len(values))
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		if _, ok := set[value]; ok {
+			continue
+		}
+		set[value] = struct{}{}
+		result = append(result, value)
+	}
+	return result
+}
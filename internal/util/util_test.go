@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/require"
+	"github.com/wzyjerry/windranger/internal/parser"
 )
 
 func TestSnake(t *testing.T) {
@@ -62,3 +63,64 @@ func TestGetPackageName(t *testing.T) {
 	validator.Equal("publicationNested", GetPackageName("publication"))
 	validator.Equal("personNested", GetPackageName("person"))
 }
+
+func TestIsPlural(t *testing.T) {
+	validator := require.New(t)
+	validator.True(IsPlural("authors"))
+	validator.True(IsPlural("author_ids"))
+	validator.True(IsPlural("fish"))
+	validator.False(IsPlural("author"))
+	validator.True(IsSingular("author"))
+	validator.True(IsSingular("status"))
+	validator.False(IsPlural("status"))
+	validator.True(IsSingular("fish"))
+	validator.False(IsSingular("authors"))
+}
+
+func TestKebab(t *testing.T) {
+	validator := require.New(t)
+	validator.Equal("user-info", Kebab("UserInfo"))
+	validator.Equal("full-name", Kebab("full_name"))
+}
+
+func TestLanguageTypes(t *testing.T) {
+	validator := require.New(t)
+	for _, c := range []struct {
+		in            parser.Type
+		ts, py, proto string
+	}{
+		{parser.Type{Raw: "int", Name: "int64"}, "number", "int", "int64"},
+		{parser.Type{Raw: "string", Name: "string", Kind: parser.KindArray}, "string[]", "list[str]", "repeated string"},
+		{parser.Type{Raw: "datetime", Name: "Time", Package: "time"}, "Date", "datetime", "google.protobuf.Timestamp"},
+		{parser.Type{Raw: "address", Name: "Address", Kind: parser.KindOptional}, "Address | null", "Optional[Address]", "optional Address"},
+		{parser.Type{Raw: "objectid", Name: "ObjectID", Package: "primitive", Kind: parser.KindPrimaryKey}, "string", "ObjectId", "string"},
+	} {
+		validator.Equal(c.ts, TsType(c.in))
+		validator.Equal(c.py, PyType(c.in))
+		validator.Equal(c.proto, ProtoType(c.in))
+	}
+}
+
+func TestShardKeyIndexed(t *testing.T) {
+	validator := require.New(t)
+	key := func(name string, order parser.IndexOrder) *parser.IndexKey {
+		return &parser.IndexKey{Key: name, Order: order}
+	}
+	collection := &parser.Collection{
+		ShardKey: &parser.ShardKey{Keys: []*parser.IndexKey{key("tenant", parser.IndexAscending), key("_id", parser.IndexAscending)}},
+		Indexes: []*parser.Index{
+			{Name: "tenant", Keys: []*parser.IndexKey{key("tenant", parser.IndexAscending)}},
+			{Name: "tenant_id_desc", Keys: []*parser.IndexKey{key("tenant", parser.IndexAscending), key("_id", parser.IndexDescending)}},
+		},
+	}
+	validator.False(ShardKeyIndexed(collection))
+	// 以分片键为前缀的索引可以用于分片
+	collection.Indexes = append(collection.Indexes, &parser.Index{
+		Name:   "tenant_id_name",
+		Unique: true,
+		Keys:   []*parser.IndexKey{key("tenant", parser.IndexAscending), key("_id", parser.IndexAscending), key("name", parser.IndexAscending)},
+	})
+	validator.True(ShardKeyIndexed(collection))
+	validator.False(ShardKeyIndexed(&parser.Collection{}))
+	validator.False(ShardKeyIndexed(nil))
+}
@@ -0,0 +1,17 @@
+package version
+
+import "runtime/debug"
+
+// Version windranger版本，发布时通过-ldflags "-X github.com/wzyjerry/windranger/internal/version.Version=v1.2.3"设置
+var Version = ""
+
+// Get 当前版本，未设置时使用go install记录的模块版本，均不可用时为dev
+func Get() string {
+	if Version != "" {
+		return Version
+	}
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	return "dev"
+}
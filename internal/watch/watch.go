@@ -0,0 +1,145 @@
+package watch
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/wzyjerry/windranger/internal/parser"
+)
+
+// Options 监听参数
+type Options struct {
+	// Interval 轮询间隔
+	Interval time.Duration
+	// Debounce 最后一次修改后等待的时间，期间的连续修改只触发一次
+	Debounce time.Duration
+	// Exclude 判断配置目录下的文件或目录是否不监听，参数为绝对路径，
+	// 用于排除生成目录和生成的文件，避免生成结果再次触发生成
+	Exclude func(name string) bool
+}
+
+// DefaultOptions 默认监听参数
+var DefaultOptions = Options{
+	Interval: 200 * time.Millisecond,
+	Debounce: 300 * time.Millisecond,
+}
+
+// stamp 文件状态
+type stamp struct {
+	size    int64
+	modTime time.Time
+}
+
+// snapshot 配置目录下全部文件、windranger.yaml中列出的资源文件及依赖项目资源文件的状态
+//
+// 每次重新读取windranger.yaml，资源列表的增减会反映在下一次快照中；
+// 配置文件无效时只包含配置目录下的文件；exclude不为nil时跳过其排除的文件和目录，配置目录本身总是遍历
+func snapshot(root string, exclude func(name string) bool) map[string]stamp {
+	result := make(map[string]stamp)
+	add := func(name string) {
+		info, err := os.Stat(name)
+		if err != nil || info.IsDir() {
+			return
+		}
+		result[filepath.Clean(name)] = stamp{size: info.Size(), modTime: info.ModTime()}
+	}
+	_ = filepath.WalkDir(root, func(name string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		skip := name != root && exclude != nil && exclude(Absolute(name))
+		if entry.IsDir() {
+			// 跳过隐藏目录，例如.git
+			if name != root && (entry.Name()[0] == '.' || skip) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !skip {
+			add(name)
+		}
+		return nil
+	})
+	// 资源文件和依赖项目可能位于配置目录之外
+	roots := []string{root}
+	if deps, err := parser.Dependencies(root); err == nil {
+		for _, dep := range deps {
+			roots = append(roots, dep.Root(root))
+			add(path.Join(dep.Root(root), parser.Profile))
+		}
+	}
+	for _, root := range roots {
+		if resources, err := parser.Resources(root); err == nil {
+			for _, resource := range resources {
+				add(resource)
+			}
+		}
+	}
+	return result
+}
+
+// Absolute 用于比较的绝对路径，无法获取时使用清理后的路径
+func Absolute(name string) string {
+	if abs, err := filepath.Abs(name); err == nil {
+		return abs
+	}
+	return filepath.Clean(name)
+}
+
+// changes 两次快照之间新增、删除或修改的文件
+func changes(previous map[string]stamp, current map[string]stamp) []string {
+	var result []string
+	for name, s := range current {
+		if old, ok := previous[name]; !ok || old.size != s.size || !old.modTime.Equal(s.modTime) {
+			result = append(result, name)
+		}
+	}
+	for name := range previous {
+		if _, ok := current[name]; !ok {
+			result = append(result, name)
+		}
+	}
+	return result
+}
+
+// Watch 轮询配置目录，文件变化且在Debounce内没有新的变化后以排序后的变化文件调用run，直到ctx结束
+//
+// 不会在开始时调用run，调用方应先执行一次生成
+func Watch(ctx context.Context, root string, opts Options, run func(changed []string)) {
+	last := snapshot(root, opts.Exclude)
+	pending := make(map[string]struct{})
+	var modified time.Time
+	ticker := time.NewTicker(opts.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		current := snapshot(root, opts.Exclude)
+		if changed := changes(last, current); len(changed) != 0 {
+			for _, name := range changed {
+				pending[name] = struct{}{}
+			}
+			last = current
+			modified = time.Now()
+			continue
+		}
+		if len(pending) == 0 || time.Since(modified) < opts.Debounce {
+			continue
+		}
+		changed := make([]string, 0, len(pending))
+		for name := range pending {
+			changed = append(changed, name)
+		}
+		sort.Strings(changed)
+		pending = make(map[string]struct{})
+		run(changed)
+	}
+}
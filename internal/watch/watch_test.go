@@ -0,0 +1,104 @@
+package watch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func write(t *testing.T, name string, content string) {
+	require.Nil(t, os.WriteFile(name, []byte(content), os.ModePerm))
+}
+
+func profile(resources ...string) string {
+	content := "version: v1\nkind: Windranger\nresources:\n"
+	for _, resource := range resources {
+		content += "  - " + resource + "\n"
+	}
+	return content
+}
+
+func TestSnapshot(t *testing.T) {
+	validator := require.New(t)
+	dir := t.TempDir()
+	root := filepath.Join(dir, "model")
+	validator.Nil(os.MkdirAll(filepath.Join(root, ".git"), os.ModePerm))
+	write(t, filepath.Join(root, ".git", "HEAD"), "ref")
+	write(t, filepath.Join(root, "windranger.yaml"), profile("demo.yaml", "../common.yaml"))
+	write(t, filepath.Join(root, "demo.yaml"), "demo")
+	write(t, filepath.Join(dir, "common.yaml"), "common")
+	write(t, filepath.Join(dir, "other.yaml"), "other")
+
+	previous := snapshot(root, nil)
+	validator.Len(previous, 3)
+	validator.Contains(previous, filepath.Join(dir, "common.yaml"))
+	validator.Nil(changes(previous, snapshot(root, nil)))
+
+	// 从资源列表中移除的目录外文件不再被监听
+	write(t, filepath.Join(root, "windranger.yaml"), profile("demo.yaml", "../other.yaml"))
+	current := snapshot(root, nil)
+	validator.ElementsMatch([]string{
+		filepath.Join(root, "windranger.yaml"),
+		filepath.Join(dir, "common.yaml"),
+		filepath.Join(dir, "other.yaml"),
+	}, changes(previous, current))
+	validator.NotContains(current, filepath.Join(dir, "common.yaml"))
+
+	// 配置目录下的生成目录和配置目录中的生成清单不被监听
+	validator.Nil(os.MkdirAll(filepath.Join(root, "out"), os.ModePerm))
+	write(t, filepath.Join(root, "out", "demo.go"), "package out")
+	write(t, filepath.Join(root, ".windranger-manifest.json"), "{}")
+	current = snapshot(root, func(name string) bool {
+		return name == Absolute(filepath.Join(root, "out")) || name == Absolute(filepath.Join(root, ".windranger-manifest.json"))
+	})
+	validator.Contains(current, filepath.Join(root, "demo.yaml"))
+	validator.NotContains(current, filepath.Join(root, "out", "demo.go"))
+	validator.NotContains(current, filepath.Join(root, ".windranger-manifest.json"))
+}
+
+func TestWatch(t *testing.T) {
+	validator := require.New(t)
+	root := t.TempDir()
+	write(t, filepath.Join(root, "windranger.yaml"), profile("demo.yaml"))
+	write(t, filepath.Join(root, "demo.yaml"), "demo")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	runs := make(chan []string, 10)
+	done := make(chan struct{})
+	go func() {
+		Watch(ctx, root, Options{Interval: 10 * time.Millisecond, Debounce: 100 * time.Millisecond}, func(changed []string) {
+			runs <- changed
+		})
+		close(done)
+	}()
+	receive := func() []string {
+		select {
+		case changed := <-runs:
+			return changed
+		case <-time.After(5 * time.Second):
+			t.Fatal("修改未触发生成")
+			return nil
+		}
+	}
+
+	// 连续保存只触发一次
+	time.Sleep(50 * time.Millisecond)
+	for i := 0; i < 3; i++ {
+		write(t, filepath.Join(root, "demo.yaml"), "demo"+string(rune('a'+i)))
+		time.Sleep(20 * time.Millisecond)
+	}
+	validator.Equal([]string{filepath.Join(root, "demo.yaml")}, receive())
+	validator.Empty(runs)
+
+	// 新增资源
+	write(t, filepath.Join(root, "windranger.yaml"), profile("demo.yaml", "author.yaml"))
+	write(t, filepath.Join(root, "author.yaml"), "author")
+	validator.Equal([]string{filepath.Join(root, "author.yaml"), filepath.Join(root, "windranger.yaml")}, receive())
+
+	cancel()
+	<-done
+}
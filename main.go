@@ -2,15 +2,37 @@ package main
 
 import (
 	"github.com/spf13/cobra"
+	"github.com/wzyjerry/windranger/internal/command/deps"
+	"github.com/wzyjerry/windranger/internal/command/diff"
+	"github.com/wzyjerry/windranger/internal/command/format"
+	"github.com/wzyjerry/windranger/internal/command/gen"
 	"github.com/wzyjerry/windranger/internal/command/gogo"
+	"github.com/wzyjerry/windranger/internal/command/ir"
+	"github.com/wzyjerry/windranger/internal/command/lint"
+	"github.com/wzyjerry/windranger/internal/command/lsp"
+	"github.com/wzyjerry/windranger/internal/command/migrate"
+	"github.com/wzyjerry/windranger/internal/command/mongo"
+	"github.com/wzyjerry/windranger/internal/command/template"
+	"github.com/wzyjerry/windranger/internal/version"
 )
 
 func main() {
 	cmd := &cobra.Command{
-		Use: "windranger",
+		Use:     "windranger",
+		Version: version.Get(),
 	}
 	cmd.AddCommand(
 		gogo.Gogo(),
+		mongo.MongoInit(),
+		diff.Diff(),
+		migrate.Migrate(),
+		lint.Lint(),
+		format.Fmt(),
+		lsp.Lsp(),
+		ir.IR(),
+		gen.Gen(),
+		template.Template(),
+		deps.Deps(),
 	)
 	_ = cmd.Execute()
 }
@@ -0,0 +1,82 @@
+// Package linker 为解析结果填充目标语言信息
+//
+// 链接器按类型映射填充Type.Name和Type.Package，按标识符转换函数和保留字填充各定义的Ident，
+// 并计算每个包的Dependencies。链接会原地修改传入的包。
+package linker
+
+import (
+	"context"
+	"sort"
+
+	"github.com/wzyjerry/windranger/internal/generator/gogo"
+	"github.com/wzyjerry/windranger/internal/linker"
+	"github.com/wzyjerry/windranger/internal/util"
+	"github.com/wzyjerry/windranger/pkg/parser"
+)
+
+// Type 目标语言类型
+type Type struct {
+	Name string
+	// Package 类型所在的包，为空表示无需导入
+	Package string
+}
+
+// Options 链接参数
+type Options struct {
+	// Typemap 内置类型对应的目标语言类型，未映射的类型按Ident转换名称
+	Typemap map[string]Type
+	// Ident yaml名称到目标语言标识符的转换，nil时使用ProtoPascal
+	Ident func(name string) string
+	// Keywords 目标语言保留字，标识符命中时追加'_'
+	Keywords []string
+	// Transliterate 非ASCII字符转写，nil或返回false时报告错误
+	Transliterate func(r rune) (string, bool)
+}
+
+// Linker 链接器，可以重复使用
+type Linker interface {
+	// Link 链接一组包，返回链接后的包
+	Link(ctx context.Context, packages []*parser.Package) ([]*parser.Package, []error)
+}
+
+type defaultLinker struct {
+	opts Options
+}
+
+// New 创建链接器
+func New(opts Options) Linker {
+	return &defaultLinker{
+		opts: opts,
+	}
+}
+
+// Go gogo生成器使用的链接参数
+func Go() Options {
+	opts := Options{
+		Typemap:  make(map[string]Type, len(gogo.Typemap)),
+		Ident:    util.ProtoPascal,
+		Keywords: make([]string, 0, len(gogo.Ident.Keywords)),
+	}
+	for src, t := range gogo.Typemap {
+		opts.Typemap[src] = Type{Name: t.Name, Package: t.Package}
+	}
+	for keyword := range gogo.Ident.Keywords {
+		opts.Keywords = append(opts.Keywords, keyword)
+	}
+	sort.Strings(opts.Keywords)
+	return opts
+}
+
+func (l *defaultLinker) Link(ctx context.Context, packages []*parser.Package) ([]*parser.Package, []error) {
+	ident := l.opts.Ident
+	if ident == nil {
+		ident = util.ProtoPascal
+	}
+	policy := util.NewIdentPolicy(l.opts.Keywords...)
+	policy.Transliterate = l.opts.Transliterate
+	internal := linker.NewLinker().AddPackages(packages).SetFieldFunc(ident).SetIdentPolicy(policy)
+	for src, t := range l.opts.Typemap {
+		internal.AddTypemap(src, t.Name, t.Package)
+	}
+	return internal.LinkContext(ctx)
+}
@@ -0,0 +1,66 @@
+package linker_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/wzyjerry/windranger/pkg/linker"
+	"github.com/wzyjerry/windranger/pkg/parser"
+)
+
+const demo = `version: v1
+kind: Model
+metadata:
+  name: demo
+spec:
+  # 示例
+  demo:
+    id!: objectid # 主键
+    type: string # 类型
+    created_at: datetime # 创建时间
+`
+
+func parse(t *testing.T) []*parser.Package {
+	packages, errs := parser.New(parser.Options{}).Parse(context.Background(), parser.File{Name: "demo.yaml", Reader: strings.NewReader(demo)})
+	require.Nil(t, errs)
+	return packages
+}
+
+func TestGo(t *testing.T) {
+	validator := require.New(t)
+	packages, errs := linker.New(linker.Go()).Link(context.Background(), parse(t))
+	validator.Nil(errs)
+	demo := packages[0]
+	validator.Equal([]string{"primitive", "time"}, demo.Dependencies)
+	validator.Equal("Demo", demo.Structures[0].Ident)
+	fields := demo.Structures[0].Fields
+	validator.Equal("Id", fields[0].Ident)
+	validator.Equal("ObjectID", fields[0].Type.Name)
+	validator.Equal("Type", fields[1].Ident)
+	validator.Equal("Time", fields[2].Type.Name)
+}
+
+func TestOptions(t *testing.T) {
+	validator := require.New(t)
+	packages, errs := linker.New(linker.Options{
+		Typemap: map[string]linker.Type{
+			"objectid": {Name: "string"},
+			"string":   {Name: "string"},
+			"datetime": {Name: "Date"},
+		},
+		Ident:    strings.ToLower,
+		Keywords: []string{"type"},
+	}).Link(context.Background(), parse(t))
+	validator.Nil(errs)
+	fields := packages[0].Structures[0].Fields
+	validator.Equal("type_", fields[1].Ident)
+	validator.Equal("Date", fields[2].Type.Name)
+	validator.Empty(packages[0].Dependencies)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, errs = linker.New(linker.Go()).Link(ctx, parse(t))
+	validator.Equal([]error{context.Canceled}, errs)
+}
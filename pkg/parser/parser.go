@@ -0,0 +1,133 @@
+// Package parser 解析windranger模型配置
+//
+// 本包是windranger对外的稳定接口，供构建工具等嵌入使用。解析结果为包列表，
+// 各类型的含义见Package；其中的Ident、Type.Name等目标语言信息由linker包填充。
+package parser
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+
+	"github.com/wzyjerry/windranger/internal/parser"
+)
+
+type (
+	// Package 包，每个含表的模型对应一个包，不含表的模型合并为公共包
+	Package = parser.Package
+	// Structure 结构定义
+	Structure = parser.Structure
+	// Field 结构字段
+	Field = parser.Field
+	// Type 字段类型
+	Type = parser.Type
+	// Kind 字段类型的修饰
+	Kind = parser.Kind
+	// Enum 枚举定义
+	Enum = parser.Enum
+	// EnumField 枚举值
+	EnumField = parser.EnumField
+	// Deprecation 废弃标记
+	Deprecation = parser.Deprecation
+	// Collection 集合元数据
+	Collection = parser.Collection
+	// Index 索引
+	Index = parser.Index
+	// IndexKey 索引键
+	IndexKey = parser.IndexKey
+	// IndexOrder 索引键的排序方式
+	IndexOrder = parser.IndexOrder
+	// ShardKey 分片键
+	ShardKey = parser.ShardKey
+	// Position 源码位置
+	Position = parser.Position
+	// Error 带位置的解析错误，解析返回的错误大多为*Error
+	Error = parser.Error
+	// Cache 文档解析结果缓存
+	Cache = parser.Cache
+)
+
+const (
+	KindNormal     = parser.KindNormal
+	KindArray      = parser.KindArray
+	KindOptional   = parser.KindOptional
+	KindPrimaryKey = parser.KindPrimaryKey
+
+	IndexAscending  = parser.IndexAscending
+	IndexDescending = parser.IndexDescending
+	IndexText       = parser.IndexText
+	IndexHashed     = parser.IndexHashed
+
+	// CommonPackage 公共包名
+	CommonPackage = parser.CommonPackage
+)
+
+// Options 解析参数
+type Options struct {
+	// Workers 并发解析的文档数，0表示GOMAXPROCS
+	Workers int
+	// Cache 文档解析结果缓存，nil表示不缓存
+	Cache Cache
+}
+
+// File 模型文件，可以包含多个以'---'分隔的模型
+type File struct {
+	// Name 文件路径，用于定位错误
+	Name string
+	// Reader 文件内容
+	Reader io.Reader
+}
+
+// Parser 模型解析器，可以重复使用，各次解析相互独立
+type Parser interface {
+	// Parse 解析一组模型文件，文件之间可以相互引用
+	Parse(ctx context.Context, files ...File) ([]*Package, []error)
+	// ParseFS 解析fsys中dir目录下windranger.yaml列出的模型文件，资源路径相对于dir且不能超出fsys
+	//
+	// fsys可以是os.DirFS、embed.FS、zip.Reader或fstest.MapFS等
+	ParseFS(ctx context.Context, fsys fs.FS, dir string) ([]*Package, []error)
+}
+
+type defaultParser struct {
+	opts Options
+}
+
+// New 创建解析器
+func New(opts Options) Parser {
+	return &defaultParser{
+		opts: opts,
+	}
+}
+
+func (p *defaultParser) Parse(ctx context.Context, files ...File) ([]*Package, []error) {
+	internal := parser.NewParser()
+	if p.opts.Workers > 0 {
+		internal.SetWorkers(p.opts.Workers)
+	}
+	if p.opts.Cache != nil {
+		internal.SetCache(p.opts.Cache)
+	}
+	for _, file := range files {
+		if err := ctx.Err(); err != nil {
+			return nil, []error{err}
+		}
+		content, err := io.ReadAll(file.Reader)
+		if err != nil {
+			return nil, []error{fmt.Errorf("%s: %w", file.Name, err)}
+		}
+		internal.AddYamlStream(file.Name, content)
+	}
+	return internal.ParseContext(ctx)
+}
+
+func (p *defaultParser) ParseFS(ctx context.Context, fsys fs.FS, dir string) ([]*Package, []error) {
+	internal := parser.NewParser()
+	if p.opts.Workers > 0 {
+		internal.SetWorkers(p.opts.Workers)
+	}
+	if p.opts.Cache != nil {
+		internal.SetCache(p.opts.Cache)
+	}
+	return internal.AddYamlFS(fsys, dir).ParseContext(ctx)
+}
@@ -0,0 +1,105 @@
+package parser_test
+
+import (
+	"context"
+	"embed"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+	"github.com/wzyjerry/windranger/pkg/parser"
+)
+
+const (
+	demo = `version: v1
+kind: Model
+metadata:
+  name: demo
+spec:
+  # 示例
+  demo:
+    id!: objectid # 主键
+    gender: gender # 性别
+`
+	common = `version: v1
+kind: Model
+metadata:
+  name: common
+spec:
+  # 性别
+  gender:
+    - male # 男
+    - female # 女
+`
+)
+
+func TestParse(t *testing.T) {
+	validator := require.New(t)
+	p := parser.New(parser.Options{Workers: 1})
+	packages, errs := p.Parse(context.Background(),
+		parser.File{Name: "demo.yaml", Reader: strings.NewReader(demo)},
+		parser.File{Name: "common.yaml", Reader: strings.NewReader(common)},
+	)
+	validator.Nil(errs)
+	validator.Len(packages, 2)
+	validator.Equal("demo", packages[0].Name)
+	validator.Equal(parser.KindPrimaryKey, packages[0].Structures[0].Fields[0].Type.Kind)
+	validator.Equal(parser.CommonPackage, packages[1].Name)
+	validator.Equal("gender", packages[1].Enums[0].Name)
+
+	// 解析器可以重复使用
+	_, errs = p.Parse(context.Background(), parser.File{Name: "demo.yaml", Reader: strings.NewReader(strings.Replace(demo, "version: v1", "version: v0", 1))})
+	validator.Len(errs, 1)
+	validator.Equal(parser.Position{File: "demo.yaml"}, errs[0].(*parser.Error).Pos)
+}
+
+func TestParseFS(t *testing.T) {
+	validator := require.New(t)
+	fsys := fstest.MapFS{
+		"model/windranger.yaml": {Data: []byte("version: v1\nkind: Windranger\nresources:\n  - demo.yaml\n  - ../common.yaml\n")},
+		"model/demo.yaml":       {Data: []byte(demo)},
+		"common.yaml":           {Data: []byte(common)},
+	}
+	expected, errs := parser.New(parser.Options{}).Parse(context.Background(),
+		parser.File{Name: "model/demo.yaml", Reader: strings.NewReader(demo)},
+		parser.File{Name: "common.yaml", Reader: strings.NewReader(common)},
+	)
+	validator.Nil(errs)
+	packages, errs := parser.New(parser.Options{}).ParseFS(context.Background(), fsys, "model")
+	validator.Nil(errs)
+	validator.Equal(expected, packages)
+
+	_, errs = parser.New(parser.Options{}).ParseFS(context.Background(), fsys, "missing")
+	validator.Len(errs, 1)
+
+	// 资源路径不能超出根目录
+	fsys["windranger.yaml"] = &fstest.MapFile{Data: []byte("version: v1\nkind: Windranger\nresources:\n  - ../common.yaml\n")}
+	_, errs = parser.New(parser.Options{}).ParseFS(context.Background(), fsys, ".")
+	validator.EqualError(errs[0], "资源路径超出根目录: ../common.yaml")
+}
+
+//go:embed testdata/model
+var embedded embed.FS
+
+func TestParseEmbed(t *testing.T) {
+	validator := require.New(t)
+	// 一个文件中包含多个模型
+	expected, errs := parser.New(parser.Options{}).Parse(context.Background(),
+		parser.File{Name: "testdata/model/demo.yaml", Reader: strings.NewReader(demo + "---\n" + common)},
+	)
+	validator.Nil(errs)
+	validator.Len(expected, 2)
+	packages, errs := parser.New(parser.Options{}).ParseFS(context.Background(), embedded, "testdata/model")
+	validator.Nil(errs)
+	validator.Equal(expected, packages)
+}
+
+func TestParseCanceled(t *testing.T) {
+	validator := require.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	packages, errs := parser.New(parser.Options{}).Parse(ctx, parser.File{Name: "demo.yaml", Reader: strings.NewReader(demo)})
+	validator.Nil(packages)
+	validator.Equal([]error{context.Canceled}, errs)
+}